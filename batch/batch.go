@@ -0,0 +1,311 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"scraper/scraper"
+	"scraper/store"
+	"scraper/tools"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoScraper 链接没有对应的已注册数据源，这类任务不会重试
+var ErrNoScraper = errors.New("没有可用的数据源")
+
+// Progress 批次进度
+type Progress struct {
+	Total   int    // 本次运行的任务数
+	Done    int    // 成功
+	Retry   int    // 失败，稍后重试
+	Failed  int    // 失败且不再重试
+	Current string // 刚完成的链接
+	Err     error  // 刚完成的链接的错误
+}
+
+// Runner 批量抓取
+//
+// 任务状态保存在 Store 中，进程中断后用同一个批次名再次运行即可从断点继续。
+type Runner struct {
+	Store        *store.Store
+	Workers      int            // 总并发数，同时运行的多个批次共用
+	SourceLimit  map[string]int // 各数据源的并发上限，同时运行的多个批次共用
+	DefaultLimit int            // 未在 SourceLimit 中配置的数据源的并发上限，0 表示不限制
+	MaxAttempts  int            // 最大尝试次数
+	RetryDelay   time.Duration  // 第一次重试的等待时间，之后每次翻倍
+	Progress     func(Progress) // 每完成一个链接调用一次
+
+	// Scrape 抓取单个链接，为空时按链接识别数据源并调用已注册的 scraper
+	Scrape func(uri string) (*scraper.Item, error)
+	// OnItem 抓取成功后调用，在写入 Store 之后
+	OnItem func(uri string, item *scraper.Item)
+	// Taxonomy 不为空时在写入 Store 之前统一标签
+	Taxonomy *scraper.Taxonomy
+
+	limits limiter // 所有 Run 共享
+	now    func() time.Time
+}
+
+// NewRunner 创建使用默认配置的 Runner
+func NewRunner(s *store.Store) *Runner {
+	return &Runner{
+		Store:        s,
+		Workers:      8,
+		DefaultLimit: 2,
+		MaxAttempts:  3,
+		RetryDelay:   10 * time.Minute,
+	}
+}
+
+// ReadURLs 从文件中读取链接，每行一个，忽略空行和 # 开头的注释
+func ReadURLs(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// Source 识别链接所属的数据源，无法识别时返回域名
+func Source(uri string) string {
+	if code, ok := tools.ParseCode(uri); ok {
+		return code.Source
+	}
+	if u, err := url.Parse(uri); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// Run 将 urls 加入批次并抓取批次中所有可以抓取的任务
+//
+// ctx 取消后不再开始新的任务，已开始的任务会执行完毕并保存状态。
+func (r *Runner) Run(ctx context.Context, batch string, urls []string) (Progress, error) {
+	if len(urls) > 0 {
+		if err := r.Store.AddTasks(batch, urls); err != nil {
+			return Progress{}, err
+		}
+	}
+	tasks, err := r.Store.ReadyTasks(batch, r.clock())
+	if err != nil {
+		return Progress{}, err
+	}
+
+	progress := Progress{Total: len(tasks)}
+	var lock sync.Mutex
+	report := func(task store.Task, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch task.Status {
+		case store.TaskDone:
+			progress.Done++
+		case store.TaskRetry:
+			progress.Retry++
+		case store.TaskFailed:
+			progress.Failed++
+		}
+		progress.Current, progress.Err = task.Url, err
+		if r.Progress != nil {
+			r.Progress(progress)
+		}
+	}
+
+	// 只取未达到并发上限的数据源的任务，繁忙的数据源不会挡住其它数据源。
+	// 并发上限由同一个 Runner 的所有 Run 共享，多个批次同时运行时也不会超出
+	pending := newSourceQueues(tasks)
+	var saveErr error
+	wait := sync.WaitGroup{}
+	for ctx.Err() == nil && !pending.empty() {
+		task, ok, changed := r.limits.acquire(pending, r)
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-changed:
+			}
+			continue
+		}
+		wait.Add(1)
+		go func(task store.Task) {
+			defer wait.Done()
+			defer r.limits.release(Source(task.Url))
+			task, err := r.runTask(task)
+			if e := r.Store.UpdateTask(task); e != nil {
+				lock.Lock()
+				saveErr = e
+				lock.Unlock()
+			}
+			report(task, err)
+		}(task)
+	}
+	wait.Wait()
+
+	if saveErr != nil {
+		return progress, saveErr
+	}
+	return progress, ctx.Err()
+}
+
+func (r *Runner) runTask(task store.Task) (store.Task, error) {
+	task.Attempts++
+	item, err := r.scrape(task.Url)
+	if err == nil && item != nil {
+		if r.Taxonomy != nil {
+			r.Taxonomy.NormalizeItem(item)
+		}
+		task.ItemID, err = r.Store.Upsert(item)
+	}
+	if err == nil && item == nil {
+		err = errors.New("抓取结果为空")
+	}
+	if err != nil {
+		task.Error = err.Error()
+		if errors.Is(err, ErrNoScraper) || task.Attempts >= r.MaxAttempts {
+			task.Status = store.TaskFailed
+		} else {
+			task.Status = store.TaskRetry
+			task.RetryAfter = r.clock().Add(r.RetryDelay << (task.Attempts - 1))
+		}
+		return task, err
+	}
+
+	task.Status, task.Error, task.RetryAfter = store.TaskDone, "", time.Time{}
+	if r.OnItem != nil {
+		r.OnItem(task.Url, item)
+	}
+	return task, nil
+}
+
+func (r *Runner) scrape(uri string) (item *scraper.Item, err error) {
+	// 单个链接出错不能影响整个批次
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("抓取 %s 时发生 panic: %v", uri, e)
+		}
+	}()
+	if r.Scrape != nil {
+		return r.Scrape(uri)
+	}
+	code, ok := tools.ParseCode(uri)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoScraper, uri)
+	}
+	s, ok := scraper.Lookup(code.Source)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoScraper, uri)
+	}
+	return s.GetItem(code.URL())
+}
+
+func (r *Runner) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// sourceQueues 按数据源分组的待执行任务
+type sourceQueues struct {
+	order  []string
+	tasks  map[string][]store.Task
+	cursor int // 下次从哪个数据源开始查找，轮流分配给各数据源
+}
+
+func newSourceQueues(tasks []store.Task) *sourceQueues {
+	q := &sourceQueues{tasks: make(map[string][]store.Task)}
+	for _, task := range tasks {
+		source := Source(task.Url)
+		if _, ok := q.tasks[source]; !ok {
+			q.order = append(q.order, source)
+		}
+		q.tasks[source] = append(q.tasks[source], task)
+	}
+	return q
+}
+
+func (q *sourceQueues) empty() bool {
+	for _, tasks := range q.tasks {
+		if len(tasks) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// next 取出下一个 available 返回 true 的数据源的任务，没有时返回 false
+func (q *sourceQueues) next(available func(source string) bool) (store.Task, bool) {
+	for i := range q.order {
+		n := (q.cursor + i) % len(q.order)
+		source := q.order[n]
+		tasks := q.tasks[source]
+		if len(tasks) == 0 || !available(source) {
+			continue
+		}
+		q.tasks[source] = tasks[1:]
+		q.cursor = n + 1
+		return tasks[0], true
+	}
+	return store.Task{}, false
+}
+
+// limiter 记录正在执行的任务数，零值可以直接使用
+type limiter struct {
+	lock    sync.Mutex
+	total   int
+	running map[string]int
+	changed chan struct{} // 有任务结束时关闭
+}
+
+// acquire 从 q 中取出一个不超出 r 的并发上限的任务，没有时返回在有任务结束时关闭的 channel
+func (l *limiter) acquire(q *sourceQueues, r *Runner) (store.Task, bool, <-chan struct{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+		l.running = make(map[string]int)
+	}
+	workers := r.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	if l.total >= workers {
+		return store.Task{}, false, l.changed
+	}
+	task, ok := q.next(func(source string) bool {
+		n, ok := r.SourceLimit[source]
+		if !ok {
+			n = r.DefaultLimit
+		}
+		return n <= 0 || l.running[source] < n
+	})
+	if !ok {
+		return store.Task{}, false, l.changed
+	}
+	l.total++
+	l.running[Source(task.Url)]++
+	return task, true, nil
+}
+
+// release 数据源的一个任务执行完毕
+func (l *limiter) release(source string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.total--
+	l.running[source]--
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
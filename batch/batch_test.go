@@ -0,0 +1,212 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"scraper/scraper"
+	"scraper/store"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *store.Store {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestRunner_Run(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Now()
+	var fail sync.Map
+	fail.Store("https://2dfan.org/subjects/2", true)
+
+	var running, maxRunning int32
+	r := NewRunner(s)
+	r.Workers = 4
+	r.SourceLimit = map[string]int{"2dfan": 1}
+	r.RetryDelay = time.Minute
+	r.now = func() time.Time { return now }
+	r.Scrape = func(uri string) (*scraper.Item, error) {
+		if Source(uri) == "2dfan" {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := fail.Load(uri); ok {
+			return nil, errors.New("boom")
+		}
+		return &scraper.Item{Name: uri, Origin: uri}, nil
+	}
+	var reports int
+	r.Progress = func(p Progress) { reports++ }
+
+	urls := []string{
+		"https://2dfan.org/subjects/1",
+		"https://2dfan.org/subjects/2",
+		"https://2dfan.org/subjects/3",
+		"ftp://unknown/4",
+	}
+	p, err := r.Run(context.Background(), "nightly", urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 4 || p.Done != 3 || p.Retry != 1 || reports != 4 {
+		t.Errorf("unexpected progress %+v, reports %d", p, reports)
+	}
+	if maxRunning != 1 {
+		t.Errorf("source limit not respected, max concurrent = %d", maxRunning)
+	}
+
+	// 未到重试时间时不会再次抓取
+	p, err = r.Run(context.Background(), "nightly", urls)
+	if err != nil || p.Total != 0 {
+		t.Errorf("expected nothing to run, got %+v, %v", p, err)
+	}
+
+	// 到达重试时间后只重试失败的链接
+	fail.Delete("https://2dfan.org/subjects/2")
+	now = now.Add(2 * time.Minute)
+	p, err = r.Run(context.Background(), "nightly", nil)
+	if err != nil || p.Total != 1 || p.Done != 1 {
+		t.Errorf("unexpected retry progress %+v, %v", p, err)
+	}
+	counts, err := s.TaskCounts("nightly")
+	if err != nil || counts[store.TaskDone] != 4 {
+		t.Errorf("unexpected counts %v, %v", counts, err)
+	}
+	if items, _ := s.Find(store.Query{}); len(items) != 4 {
+		t.Errorf("expected 4 stored items, got %d", len(items))
+	}
+}
+
+func TestRunner_BusySource(t *testing.T) {
+	s := openTestStore(t)
+	r := NewRunner(s)
+	r.Workers = 2
+	r.SourceLimit = map[string]int{"2dfan": 1}
+	// 2dfan 的任务在其它数据源的任务完成前不会结束，繁忙的 2dfan 占用 worker 时会超时
+	others := make(chan struct{})
+	var remaining int32 = 2
+	r.Scrape = func(uri string) (*scraper.Item, error) {
+		if Source(uri) != "2dfan" {
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				close(others)
+			}
+			return &scraper.Item{Name: uri, Origin: uri}, nil
+		}
+		select {
+		case <-others:
+			return &scraper.Item{Name: uri, Origin: uri}, nil
+		case <-time.After(time.Second):
+			return nil, errors.New("other sources are blocked")
+		}
+	}
+	p, err := r.Run(context.Background(), "b", []string{
+		"https://2dfan.org/subjects/1",
+		"https://2dfan.org/subjects/2",
+		"https://example.com/1",
+		"https://example.com/2",
+	})
+	if err != nil || p.Done != 4 {
+		t.Errorf("busy source should not hold workers: %+v, %v", p, err)
+	}
+}
+
+func TestRunner_NoScraper(t *testing.T) {
+	s := openTestStore(t)
+	r := NewRunner(s)
+	p, err := r.Run(context.Background(), "b", []string{"https://example.com/unknown"})
+	if err != nil || p.Failed != 1 {
+		t.Errorf("unknown url should fail without retry: %+v, %v", p, err)
+	}
+}
+
+func TestRunner_Taxonomy(t *testing.T) {
+	s := openTestStore(t)
+	r := NewRunner(s)
+	r.Taxonomy = &scraper.Taxonomy{Categories: []scraper.TagCategory{{Identity: "genre", Name: "类型", Tags: []scraper.CanonicalTag{
+		{Identity: "nakige", Name: "泣きゲー", Aliases: []string{"催泪"}},
+	}}}}
+	if err := r.Taxonomy.Build(); err != nil {
+		t.Fatal(err)
+	}
+	r.Scrape = func(uri string) (*scraper.Item, error) {
+		return &scraper.Item{Name: "a", Origin: uri, Tags: []scraper.Tag{{Item: []scraper.TagItem{{Identity: "催泪", Name: "催泪"}}}}}, nil
+	}
+	if p, err := r.Run(context.Background(), "b", []string{"https://2dfan.org/subjects/1"}); err != nil || p.Done != 1 {
+		t.Fatalf("unexpected progress %+v, %v", p, err)
+	}
+	if items, _ := s.Find(store.Query{Tag: "nakige"}); len(items) != 1 || items[0].Tags[0].Category.Identity != "genre" {
+		t.Errorf("tags should be normalized before storing, got %+v", items)
+	}
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	s := openTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRunner(s)
+	r.Workers = 1
+	r.Scrape = func(uri string) (*scraper.Item, error) {
+		cancel()
+		return &scraper.Item{Origin: uri}, nil
+	}
+	p, err := r.Run(ctx, "b", []string{"a://1", "a://2", "a://3"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled, got %v", err)
+	}
+	// 已开始的任务会完成，剩余的任务留到下次运行
+	if p.Done != 1 {
+		t.Errorf("expected the in-flight task to finish, got %+v", p)
+	}
+	tasks, _ := s.ReadyTasks("b", time.Now())
+	if len(tasks) != 2 {
+		t.Errorf("expected 2 remaining tasks, got %d", len(tasks))
+	}
+}
+
+func TestReadURLs(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "urls.txt")
+	_ = os.WriteFile(name, []byte("# comment\nhttps://a\n\n  https://b  \n"), 0644)
+	urls, err := ReadURLs(name)
+	if err != nil || len(urls) != 2 || urls[1] != "https://b" {
+		t.Errorf("ReadURLs = %v, %v", urls, err)
+	}
+}
+
+func TestEnqueueReleases(t *testing.T) {
+	s := openTestStore(t)
+	releases := []scraper.Release{
+		{Source: "getchu", Name: "a", Uri: "https://www.getchu.com/soft.phtml?id=1232405"},
+		{Source: "fanza", Name: "b", Uri: "https://www.dmm.co.jp/mono/pcgame/-/detail/=/cid=1234abc/"},
+		{Source: "getchu", Name: "c", Uri: "https://example.com/unknown"},
+	}
+	n, skipped, err := EnqueueReleases(s, "calendar", releases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fanza 尚无详情页 scraper，不会加入批次，跳过的数量返回给调用方
+	if n != 1 || skipped != 2 {
+		t.Fatalf("expected 1 url enqueued and 2 skipped, got %d, %d", n, skipped)
+	}
+	tasks, err := s.Tasks("calendar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Url != releases[0].Uri {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+}
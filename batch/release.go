@@ -0,0 +1,35 @@
+package batch
+
+import (
+	"scraper/scraper"
+	"scraper/store"
+	"scraper/tools"
+)
+
+// ReleaseURLs 返回发售表中有对应 scraper 的详情页链接，以及因为没有 scraper 而跳过的作品数
+//
+// 例如 fanza 尚无详情页 scraper，fanza 发售表中的作品都会被跳过。
+func ReleaseURLs(releases []scraper.Release) (urls []string, skipped int) {
+	for _, r := range releases {
+		code, ok := tools.ParseCode(r.Uri)
+		if !ok {
+			skipped++
+			continue
+		}
+		if _, ok = scraper.Lookup(code.Source); !ok {
+			skipped++
+			continue
+		}
+		urls = append(urls, r.Uri)
+	}
+	return urls, skipped
+}
+
+// EnqueueReleases 将发售表中的作品加入批次等待完整抓取，返回加入的数量和没有对应 scraper 而跳过的数量
+func EnqueueReleases(s *store.Store, batch string, releases []scraper.Release) (added, skipped int, err error) {
+	urls, skipped := ReleaseURLs(releases)
+	if len(urls) == 0 {
+		return 0, skipped, nil
+	}
+	return len(urls), skipped, s.AddTasks(batch, urls)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"scraper/library"
+	"scraper/scraper"
+	"scraper/store"
+	"syscall"
+)
+
+func main() {
+	dbPath := flag.String("db", "items.db", "SQLite 数据库路径")
+	force := flag.Bool("force", false, "bangumi 上的收藏比本地新时仍然覆盖")
+	dryRun := flag.Bool("dry-run", false, "只统计需要同步的条目，不修改 bangumi")
+	user := flag.String("user", "", "使用 OAuth 授权的用户名，为空时使用环境变量 BANGUMI_TOKEN")
+	clientID := flag.String("client-id", os.Getenv("BANGUMI_CLIENT_ID"), "OAuth 应用 App ID")
+	clientSecret := flag.String("client-secret", os.Getenv("BANGUMI_CLIENT_SECRET"), "OAuth 应用 App Secret")
+	redirect := flag.String("redirect", "http://127.0.0.1:8765/callback", "OAuth 回调地址，需与应用设置一致")
+	tokenDir := flag.String("tokens", "tokens", "OAuth 令牌保存目录")
+	flag.Parse()
+
+	s, err := store.Open(*dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *user != "" {
+		oauth := scraper.NewBangumiOAuth(*clientID, *clientSecret, *redirect, &scraper.FileTokenStore{Dir: *tokenDir})
+		if _, err = oauth.Store.Load(*user); errors.Is(err, scraper.ErrTokenNotFound) {
+			_, err = oauth.Authorize(ctx, *user, func(authURL string) error {
+				log.Println("请在浏览器中打开以下地址完成授权:", authURL)
+				return nil
+			})
+		}
+		if err != nil {
+			log.Println("授权失败:", err)
+			_ = s.Close()
+			os.Exit(1)
+		}
+		scraper.BangumiScraper.Token = oauth.TokenSource(*user)
+	}
+
+	sync := library.NewCollectionSync(s, scraper.BangumiScraper)
+	sync.Force, sync.DryRun = *force, *dryRun
+	result, err := sync.Sync(ctx)
+	for _, e := range result.Errors {
+		log.Println(e)
+	}
+	log.Printf("更新 %d，一致 %d，冲突 %d，失败 %d", result.Updated, result.Unchanged, result.Conflicts, result.Failed)
+	if err != nil {
+		log.Println("同步中断:", err)
+		_ = s.Close()
+		os.Exit(1)
+	}
+}
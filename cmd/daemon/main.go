@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"scraper/batch"
+	"scraper/daemon"
+	"scraper/scraper"
+	"scraper/store"
+	"syscall"
+)
+
+func main() {
+	configPath := flag.String("config", "daemon.json", "配置文件路径")
+	flag.Parse()
+
+	config, err := daemon.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobs, err := config.BuildJobs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := config.BangumiToken()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if token != nil {
+		scraper.BangumiScraper.Token = token
+	} else if scraper.BangumiScraper.Token == nil {
+		log.Println("bangumi 未配置令牌，无法获取 R18 条目，可以设置环境变量 BANGUMI_TOKEN 或在配置文件中设置 bangumi.user")
+	}
+	s, err := store.Open(config.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	runner := batch.NewRunner(s)
+	if config.Workers > 0 {
+		runner.Workers = config.Workers
+	}
+	if config.DefaultLimit > 0 {
+		runner.DefaultLimit = config.DefaultLimit
+	}
+	if config.MaxAttempts > 0 {
+		runner.MaxAttempts = config.MaxAttempts
+	}
+	if delay, err := config.RetryDelayDuration(); err != nil {
+		log.Fatal(err)
+	} else if delay > 0 {
+		runner.RetryDelay = delay
+	}
+	runner.SourceLimit = config.SourceLimit
+	if config.Tags != "" {
+		if runner.Taxonomy, err = scraper.LoadTaxonomy(config.Tags); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// 第二次收到信号时直接退出
+		stop()
+		log.Println("收到退出信号，等待正在进行的抓取完成")
+	}()
+
+	d := &daemon.Daemon{Store: s, Runner: runner, Jobs: jobs}
+	if err = d.Run(ctx); err != nil {
+		log.Println("daemon 退出:", err)
+		_ = s.Close()
+		os.Exit(1)
+	}
+	log.Println("daemon 已退出")
+}
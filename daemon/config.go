@@ -0,0 +1,138 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"scraper/scraper"
+	"time"
+)
+
+// Config 守护进程配置文件
+type Config struct {
+	DB           string         `json:"db"`            // SQLite 数据库路径
+	Workers      int            `json:"workers"`       // 总并发数
+	SourceLimit  map[string]int `json:"source_limit"`  // 各数据源并发上限
+	DefaultLimit int            `json:"default_limit"` // 其它数据源并发上限
+	MaxAttempts  int            `json:"max_attempts"`  // 最大尝试次数
+	RetryDelay   string         `json:"retry_delay"`   // 首次重试等待时间，例如 10m
+	Tags         string         `json:"tags"`          // 标签映射文件路径，为空时不统一标签
+	Bangumi      BangumiConfig  `json:"bangumi"`
+	Jobs         []JobConfig    `json:"jobs"`
+}
+
+// BangumiConfig bangumi 授权配置，不带授权时 bangumi 不返回 R18 条目
+//
+// 守护进程不会打开浏览器授权，令牌需要先用 bgmsync -user 授权并保存到 Tokens 目录。
+type BangumiConfig struct {
+	User         string `json:"user"`          // OAuth 授权的用户名，为空时使用环境变量 BANGUMI_TOKEN
+	ClientID     string `json:"client_id"`     // 为空时使用环境变量 BANGUMI_CLIENT_ID
+	ClientSecret string `json:"client_secret"` // 为空时使用环境变量 BANGUMI_CLIENT_SECRET
+	RedirectURI  string `json:"redirect_uri"`  // 需与授权时一致，默认与 bgmsync 相同
+	Tokens       string `json:"tokens"`        // 令牌保存目录，默认 tokens
+}
+
+// JobConfig 定时任务配置
+type JobConfig struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"` // cron 表达式，例如 "0 3 * * *" 或 "@monthly"
+	Select   string   `json:"select"`   // upcoming、released、calendar、brands、urls 或 file
+	Source   string   `json:"source"`   // upcoming/released 时只选该数据源，calendar 时为 getchu，fanza 尚无详情页 scraper 不能使用
+	Months   int      `json:"months"`   // calendar 时除本月外再往后看几个月
+	URLs     []string `json:"urls"`     // select 为 urls 时使用，为 brands 时是品牌页面
+	File     string   `json:"file"`     // select 为 file 时使用
+}
+
+// LoadConfig 读取 JSON 配置文件
+func LoadConfig(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", name, err)
+	}
+	return c, nil
+}
+
+// RetryDelayDuration 解析重试等待时间，未配置时返回 0
+func (c *Config) RetryDelayDuration() (time.Duration, error) {
+	if c.RetryDelay == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.RetryDelay)
+}
+
+// BangumiToken 根据配置返回 bangumi 的令牌来源，没有配置用户时返回 nil
+func (c *Config) BangumiToken() (scraper.TokenSource, error) {
+	b := c.Bangumi
+	if b.User == "" {
+		return nil, nil
+	}
+	if b.ClientID == "" {
+		b.ClientID = os.Getenv("BANGUMI_CLIENT_ID")
+	}
+	if b.ClientSecret == "" {
+		b.ClientSecret = os.Getenv("BANGUMI_CLIENT_SECRET")
+	}
+	if b.RedirectURI == "" {
+		b.RedirectURI = "http://127.0.0.1:8765/callback"
+	}
+	if b.Tokens == "" {
+		b.Tokens = "tokens"
+	}
+	oauth := scraper.NewBangumiOAuth(b.ClientID, b.ClientSecret, b.RedirectURI, &scraper.FileTokenStore{Dir: b.Tokens})
+	if _, err := oauth.Store.Load(b.User); err != nil {
+		return nil, fmt.Errorf("读取 bangumi 用户 %s 的令牌失败，请先使用 bgmsync -user 授权: %w", b.User, err)
+	}
+	return oauth.TokenSource(b.User), nil
+}
+
+// BuildJobs 根据配置生成定时任务
+func (c *Config) BuildJobs() ([]Job, error) {
+	jobs := make([]Job, 0, len(c.Jobs))
+	seen := make(map[string]bool)
+	for _, jc := range c.Jobs {
+		if jc.Name == "" || seen[jc.Name] {
+			return nil, fmt.Errorf("定时任务名称 %q 为空或重复", jc.Name)
+		}
+		seen[jc.Name] = true
+		schedule, err := ParseSchedule(jc.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("定时任务 %s: %w", jc.Name, err)
+		}
+		job := Job{Name: jc.Name, Schedule: schedule}
+		switch jc.Select {
+		case "upcoming":
+			job.Select = Upcoming(jc.Source)
+		case "released":
+			job.Select = Released(jc.Source)
+		case "calendar":
+			var source scraper.CalendarSource
+			switch jc.Source {
+			case "getchu":
+				source = scraper.GetChuScraper
+			case "fanza":
+				source = scraper.FanzaGamesScraper
+			default:
+				return nil, fmt.Errorf("定时任务 %s: 数据源 %q 没有发售表", jc.Name, jc.Source)
+			}
+			// 发售表只提供详情页链接，没有详情页 scraper 时选不出任何链接
+			if _, ok := scraper.Lookup(jc.Source); !ok {
+				return nil, fmt.Errorf("定时任务 %s: 数据源 %q 没有详情页 scraper，无法抓取发售表中的作品", jc.Name, jc.Source)
+			}
+			job.Select = Calendar(source, jc.Months)
+		case "brands":
+			job.Select = Brands(jc.URLs...)
+		case "urls":
+			job.Select = URLs(jc.URLs...)
+		case "file":
+			job.Select = URLFile(jc.File)
+		default:
+			return nil, fmt.Errorf("定时任务 %s: 未知的 select %q", jc.Name, jc.Select)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
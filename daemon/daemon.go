@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"scraper/batch"
+	"scraper/store"
+	"sync"
+	"time"
+)
+
+// Selector 选出每次定时任务需要抓取的链接
+type Selector func(s *store.Store, now time.Time) ([]string, error)
+
+// Job 定时任务
+type Job struct {
+	Name     string // 同时作为批次名，任务队列保存在该批次中
+	Schedule *Schedule
+	Select   Selector
+}
+
+// Daemon 按计划定期抓取
+//
+// 每次触发时将选出的链接写入任务队列再交给 batch.Runner 执行，
+// 进程重启后会先把上次未完成的队列跑完，错过的触发会立即补跑一次。
+// 新一轮抓取不会清除等待重试的链接。
+type Daemon struct {
+	Store  *store.Store
+	Runner *batch.Runner
+	Jobs   []Job
+	Logf   func(format string, args ...interface{})
+
+	now func() time.Time
+}
+
+// Run 运行直到 ctx 被取消
+//
+// 每个定时任务在单独的 goroutine 中执行，耗时较长的任务不会推迟其它任务，同一个任务不会同时执行多次。
+// 队列中有等待重试的链接时，会在最早的重试时间再次执行队列。
+// ctx 取消后不再开始新的抓取，等待正在执行的 GetItem 执行完毕并保存结果后返回。
+func (d *Daemon) Run(ctx context.Context) error {
+	next := make([]time.Time, len(d.Jobs))
+	for i, job := range d.Jobs {
+		last, err := d.Store.JobLastRun(job.Name)
+		if err != nil {
+			return err
+		}
+		next[i] = job.Schedule.Next(d.clock())
+		if !last.IsZero() {
+			if missed := job.Schedule.Next(last); !missed.IsZero() && missed.Before(next[i]) {
+				next[i] = missed
+			}
+		}
+	}
+
+	retry := make([]time.Time, len(d.Jobs))
+	running := make([]bool, len(d.Jobs))
+	results := make(chan jobResult, len(d.Jobs))
+	var wait sync.WaitGroup
+	defer wait.Wait()
+	start := func(i int, fire bool) {
+		running[i], retry[i] = true, time.Time{}
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			results <- d.run(ctx, i, fire)
+		}()
+	}
+	// 先处理上次遗留的队列
+	for i := range d.Jobs {
+		start(i, false)
+	}
+
+	for {
+		wake := make([]time.Time, len(d.Jobs))
+		idle := true
+		for i := range d.Jobs {
+			if running[i] {
+				idle = false
+				continue
+			}
+			wake[i] = next[i]
+			if !retry[i].IsZero() && (wake[i].IsZero() || retry[i].Before(wake[i])) {
+				wake[i] = retry[i]
+			}
+		}
+		i := earliest(wake)
+		if i < 0 && idle {
+			d.logf("没有需要执行的定时任务")
+			<-ctx.Done()
+			return nil
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if i >= 0 {
+			timer = time.NewTimer(wake[i].Sub(d.clock()))
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case r := <-results:
+			if timer != nil {
+				timer.Stop()
+			}
+			job := d.Jobs[r.index]
+			running[r.index], retry[r.index] = false, r.retry
+			if r.fired {
+				next[r.index] = job.Schedule.Next(d.clock())
+			}
+			if r.err != nil && ctx.Err() == nil {
+				d.logf("定时任务 %s 执行失败: %v", job.Name, r.err)
+			}
+		case <-timeout:
+			// 到了计划时间时开始新一轮，否则只重试队列中的链接
+			start(i, !next[i].IsZero() && !next[i].After(d.clock()))
+		}
+	}
+}
+
+// jobResult 定时任务的一次执行结果
+type jobResult struct {
+	index int
+	fired bool      // 是否开始了新一轮抓取，否则只执行了队列
+	retry time.Time // 队列中最早的重试时间，没有时为零值
+	err   error
+}
+
+func (d *Daemon) run(ctx context.Context, i int, fire bool) jobResult {
+	job := d.Jobs[i]
+	r := jobResult{index: i, fired: fire}
+	if fire {
+		r.err = d.fire(ctx, job)
+	} else {
+		r.err = d.drain(ctx, job)
+	}
+	retry, err := d.Store.NextRetry(job.Name)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	r.retry = retry
+	return r
+}
+
+func (d *Daemon) fire(ctx context.Context, job Job) error {
+	start := d.clock()
+	urls, err := job.Select(d.Store, start)
+	if err != nil {
+		return err
+	}
+	d.logf("定时任务 %s 开始，共 %d 个链接", job.Name, len(urls))
+	if err = d.Store.ReplaceTasks(job.Name, urls); err != nil {
+		return err
+	}
+	if err = d.Store.SetJobLastRun(job.Name, start); err != nil {
+		return err
+	}
+	return d.drain(ctx, job)
+}
+
+func (d *Daemon) drain(ctx context.Context, job Job) error {
+	p, err := d.Runner.Run(ctx, job.Name, nil)
+	if p.Total > 0 {
+		d.logf("定时任务 %s: 成功 %d，待重试 %d，失败 %d，共 %d", job.Name, p.Done, p.Retry, p.Failed, p.Total)
+	}
+	return err
+}
+
+func (d *Daemon) logf(format string, args ...interface{}) {
+	if d.Logf != nil {
+		d.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (d *Daemon) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+func earliest(times []time.Time) int {
+	idx := -1
+	for i, t := range times {
+		if t.IsZero() {
+			continue
+		}
+		if idx < 0 || t.Before(times[idx]) {
+			idx = i
+		}
+	}
+	return idx
+}
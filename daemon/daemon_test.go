@@ -0,0 +1,272 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"scraper/batch"
+	"scraper/scraper"
+	"scraper/store"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDaemon_Run(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 上次运行遗留的队列
+	if err = s.AddTasks("daily", []string{"https://2dfan.org/subjects/1"}); err != nil {
+		t.Fatal(err)
+	}
+	// 上次运行是两天前，@daily 已错过
+	if err = s.SetJobLastRun("daily", time.Now().AddDate(0, 0, -2)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	var scraped []string
+	runner := batch.NewRunner(s)
+	runner.Scrape = func(uri string) (*scraper.Item, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		scraped = append(scraped, uri)
+		if len(scraped) == 3 {
+			cancel()
+		}
+		return &scraper.Item{Origin: uri}, nil
+	}
+
+	schedule, _ := ParseSchedule("@daily")
+	d := &Daemon{
+		Store:  s,
+		Runner: runner,
+		Jobs: []Job{{
+			Name:     "daily",
+			Schedule: schedule,
+			Select:   URLs("https://2dfan.org/subjects/2", "https://2dfan.org/subjects/3"),
+		}},
+		Logf: t.Logf,
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not stop after cancel")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scraped) != 3 || scraped[0] != "https://2dfan.org/subjects/1" {
+		t.Errorf("expected leftover task first then missed run, got %v", scraped)
+	}
+	last, _ := s.JobLastRun("daily")
+	if time.Since(last) > time.Minute {
+		t.Errorf("last run not updated: %v", last)
+	}
+}
+
+func TestDaemon_Retry(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 上一轮遗留一个等待重试的链接和一个已完成的链接
+	retryAfter := time.Now().Add(300 * time.Millisecond)
+	for _, task := range []store.Task{
+		{Batch: "daily", Url: "https://2dfan.org/subjects/1", Status: store.TaskRetry, Attempts: 1, RetryAfter: retryAfter},
+		{Batch: "daily", Url: "https://2dfan.org/subjects/3", Status: store.TaskDone, Attempts: 1},
+	} {
+		if err = s.UpdateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.SetJobLastRun("daily", time.Now().AddDate(0, 0, -2)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	var scraped []string
+	var retried time.Time
+	runner := batch.NewRunner(s)
+	runner.Scrape = func(uri string) (*scraper.Item, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		scraped = append(scraped, uri)
+		if uri == "https://2dfan.org/subjects/1" {
+			retried = time.Now()
+			cancel()
+		}
+		return &scraper.Item{Origin: uri}, nil
+	}
+	schedule, _ := ParseSchedule("@daily")
+	d := &Daemon{
+		Store:  s,
+		Runner: runner,
+		Jobs: []Job{{
+			Name:     "daily",
+			Schedule: schedule,
+			Select:   URLs("https://2dfan.org/subjects/1", "https://2dfan.org/subjects/2"),
+		}},
+		Logf: t.Logf,
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not wake up for the retry")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新一轮不会清除等待重试的链接，也不会提前重试
+	if len(scraped) != 2 || scraped[0] != "https://2dfan.org/subjects/2" || retried.Before(retryAfter) {
+		t.Errorf("expected the retry to wait until %v, got %v at %v", retryAfter, scraped, retried)
+	}
+	tasks, _ := s.Tasks("daily")
+	if len(tasks) != 2 {
+		t.Errorf("finished tasks of the last round should be replaced, got %+v", tasks)
+	}
+}
+
+func TestDaemon_SharedSourceLimit(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	var running, maxRunning, scraped int
+	runner := batch.NewRunner(s)
+	runner.SourceLimit = map[string]int{"2dfan": 1}
+	runner.Scrape = func(uri string) (*scraper.Item, error) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		running--
+		if scraped++; scraped == 4 {
+			cancel()
+		}
+		return &scraper.Item{Origin: uri}, nil
+	}
+
+	// 两个定时任务同时执行，抓取同一个数据源
+	schedule, _ := ParseSchedule("@daily")
+	d := &Daemon{
+		Store:  s,
+		Runner: runner,
+		Logf:   t.Logf,
+	}
+	for _, name := range []string{"a", "b"} {
+		if err = s.AddTasks(name, []string{"https://2dfan.org/subjects/1" + name, "https://2dfan.org/subjects/2" + name}); err != nil {
+			t.Fatal(err)
+		}
+		d.Jobs = append(d.Jobs, Job{Name: name, Schedule: schedule, Select: URLs()})
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not finish the queues")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scraped != 4 || maxRunning != 1 {
+		t.Errorf("source limit should be shared by all jobs, scraped %d, max concurrent %d", scraped, maxRunning)
+	}
+}
+
+func TestConfig_BuildJobs(t *testing.T) {
+	c := &Config{Jobs: []JobConfig{
+		{Name: "upcoming", Schedule: "0 3 * * *", Select: "upcoming", Source: "getchu"},
+		{Name: "released", Schedule: "@monthly", Select: "released"},
+	}}
+	jobs, err := c.BuildJobs()
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("BuildJobs = %v, %v", jobs, err)
+	}
+	c.Jobs = append(c.Jobs, JobConfig{Name: "upcoming", Schedule: "@daily", Select: "urls"})
+	if _, err = c.BuildJobs(); err == nil {
+		t.Error("duplicate job names should fail")
+	}
+	c.Jobs = []JobConfig{{Name: "x", Schedule: "@daily", Select: "nope"}}
+	if _, err = c.BuildJobs(); err == nil {
+		t.Error("unknown selector should fail")
+	}
+	// fanza 没有详情页 scraper，发售表中的作品都无法抓取
+	c.Jobs = []JobConfig{{Name: "x", Schedule: "@daily", Select: "calendar", Source: "fanza"}}
+	if _, err = c.BuildJobs(); err == nil {
+		t.Error("calendar source without a detail scraper should fail")
+	}
+}
+
+func TestConfig_BangumiToken(t *testing.T) {
+	c := &Config{}
+	if token, err := c.BangumiToken(); token != nil || err != nil {
+		t.Errorf("no user should mean no token source, got %v, %v", token, err)
+	}
+	c.Bangumi = BangumiConfig{User: "koi", Tokens: t.TempDir()}
+	if _, err := c.BangumiToken(); !errors.Is(err, scraper.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+	tokens := &scraper.FileTokenStore{Dir: c.Bangumi.Tokens}
+	if err := tokens.Save("koi", &scraper.OAuthToken{AccessToken: "a", Expiry: time.Now().Add(24 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := c.BangumiToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := token.Token(); err != nil || got != "a" {
+		t.Errorf("Token() = %q, %v", got, err)
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	for _, item := range []*scraper.Item{
+		{Origin: "https://www.getchu.com/soft.phtml?id=1", ReleaseDate: "2026/10/30"},
+		{Origin: "https://2dfan.org/subjects/2", ReleaseDate: "2026/11/30"},
+		{Origin: "https://www.getchu.com/soft.phtml?id=3", ReleaseDate: "2020/01/01"},
+	} {
+		if _, err = s.Upsert(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	urls, err := Upcoming("getchu")(s, now)
+	if err != nil || len(urls) != 1 || urls[0] != "https://www.getchu.com/soft.phtml?id=1" {
+		t.Errorf("Upcoming = %v, %v", urls, err)
+	}
+	urls, err = Released("")(s, now)
+	if err != nil || len(urls) != 1 || urls[0] != "https://www.getchu.com/soft.phtml?id=3" {
+		t.Errorf("Released = %v, %v", urls, err)
+	}
+}
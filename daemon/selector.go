@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"log"
+	"scraper/batch"
+	"scraper/scraper"
+	"scraper/store"
+	"time"
+)
+
+// URLs 固定的链接列表
+func URLs(urls ...string) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		return urls, nil
+	}
+}
+
+// URLFile 每次触发时重新读取链接文件
+func URLFile(name string) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		return batch.ReadURLs(name)
+	}
+}
+
+// Upcoming 库中发售日在今天及以后的条目，source 不为空时只选该数据源
+func Upcoming(source string) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		return origins(s, store.Query{From: now.Format("2006-01-02")}, source)
+	}
+}
+
+// Released 库中发售日在今天之前的条目，source 不为空时只选该数据源
+func Released(source string) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		return origins(s, store.Query{To: now.AddDate(0, 0, -1).Format("2006-01-02")}, source)
+	}
+}
+
+// Calendar 发售表中本月及之后 months 个月的作品，只选有对应 scraper 的链接
+func Calendar(source scraper.CalendarSource, months int) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		var urls []string
+		for i := 0; i <= months; i++ {
+			t := time.Date(now.Year(), now.Month()+time.Month(i), 1, 0, 0, 0, 0, now.Location())
+			releases, err := source.GetReleaseCalendar(t.Year(), t.Month())
+			if err != nil {
+				return nil, err
+			}
+			found, skipped := batch.ReleaseURLs(releases)
+			if skipped > 0 {
+				log.Printf("%d 年 %d 月的发售表中有 %d 个作品没有对应的 scraper，已跳过", t.Year(), t.Month(), skipped)
+			}
+			urls = append(urls, found...)
+		}
+		return urls, nil
+	}
+}
+
+// Brands 品牌页面中列出的所有作品，只选有对应 scraper 的链接
+func Brands(uris ...string) Selector {
+	return func(s *store.Store, now time.Time) ([]string, error) {
+		var urls []string
+		for _, uri := range uris {
+			brand, err := scraper.GetBrand(uri)
+			if err != nil {
+				return nil, err
+			}
+			found, skipped := batch.ReleaseURLs(brand.Works)
+			if skipped > 0 {
+				log.Printf("品牌 %s 有 %d 个作品没有对应的 scraper，已跳过", uri, skipped)
+			}
+			urls = append(urls, found...)
+		}
+		return urls, nil
+	}
+}
+
+func origins(s *store.Store, q store.Query, source string) ([]string, error) {
+	items, err := s.Find(q)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, item := range items {
+		if src, _ := store.SourceID(item); source != "" && src != source {
+			continue
+		}
+		urls = append(urls, item.Origin)
+	}
+	return urls, nil
+}
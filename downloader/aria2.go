@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Aria2 aria2 JSON-RPC 客户端
+type Aria2 struct {
+	Uri    string // 例如 http://127.0.0.1:6800/jsonrpc
+	Secret string // --rpc-secret
+	Client *http.Client
+
+	id int64
+}
+
+// Add 添加任务，aria2 没有分类和标签的概念，因此只使用保存路径
+func (a *Aria2) Add(task *Task) error {
+	if err := task.validate(); err != nil {
+		return err
+	}
+	options := map[string]string{}
+	if task.SavePath != "" {
+		options["dir"] = task.SavePath
+	}
+
+	var params []interface{}
+	if a.Secret != "" {
+		params = append(params, "token:"+a.Secret)
+	}
+	method := "aria2.addUri"
+	if task.Torrent != "" {
+		data, err := task.torrentData()
+		if err != nil {
+			return err
+		}
+		method = "aria2.addTorrent"
+		params = append(params, base64.StdEncoding.EncodeToString(data), []string{}, options)
+	} else {
+		params = append(params, []string{task.Magnet}, options)
+	}
+
+	_, err := a.call(method, params)
+	return err
+}
+
+func (a *Aria2) call(method string, params []interface{}) (gjson.Result, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      strconv.FormatInt(atomic.AddInt64(&a.id, 1), 10),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Post(a.Uri, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if e := gjson.GetBytes(data, "error"); e.Exists() {
+		return gjson.Result{}, fmt.Errorf("aria2 %s 失败: %s", method, e.Get("message").String())
+	}
+	if res.StatusCode != http.StatusOK {
+		return gjson.Result{}, fmt.Errorf("aria2 请求失败 status=%d", res.StatusCode)
+	}
+	return gjson.GetBytes(data, "result"), nil
+}
@@ -0,0 +1,82 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"scraper/scraper"
+	"strings"
+)
+
+// Task 一个下载任务，磁链与种子文件至少提供一个
+type Task struct {
+	Magnet   string   // 磁力链接
+	Torrent  string   // 本地 .torrent 文件路径，优先于磁链
+	Category string   // 分类
+	SavePath string   // 保存路径
+	Tags     []string // 标签
+}
+
+// Client 下载客户端
+type Client interface {
+	Add(task *Task) error
+}
+
+var ErrEmptyTask = errors.New("下载任务缺少磁链和种子文件")
+
+// NewTask 根据 Item 生成下载任务，保存路径为 baseDir/品牌/名称
+func NewTask(item *scraper.Item, baseDir, category string, tags ...string) *Task {
+	return NewTaskWithTitle(item, baseDir, category, nil, tags...)
+}
+
+// NewTaskWithTitle 与 NewTask 相同，保存路径中的名称按 languages 的语言偏好选择，例如 zh、ja
+func NewTaskWithTitle(item *scraper.Item, baseDir, category string, languages []string, tags ...string) *Task {
+	savePath := baseDir
+	if baseDir != "" {
+		var elems []string
+		if brand := sanitize(item.Brand); brand != "" {
+			elems = append(elems, brand)
+		}
+		name := item.Name
+		if len(languages) > 0 {
+			name = item.Title(languages...)
+		}
+		if name = sanitize(name); name != "" {
+			elems = append(elems, name)
+		}
+		savePath = filepath.Join(append([]string{baseDir}, elems...)...)
+	}
+	return &Task{
+		Magnet:   item.Magnet,
+		Torrent:  item.BtFile,
+		Category: category,
+		SavePath: savePath,
+		Tags:     tags,
+	}
+}
+
+func (t *Task) validate() error {
+	if t.Magnet == "" && t.Torrent == "" {
+		return ErrEmptyTask
+	}
+	return nil
+}
+
+func (t *Task) torrentData() ([]byte, error) {
+	return os.ReadFile(t.Torrent)
+}
+
+// sanitize 去除路径中不允许出现的字符
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Trim(strings.TrimSpace(name), ".")
+}
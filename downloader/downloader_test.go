@@ -0,0 +1,172 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"scraper/scraper"
+	"testing"
+)
+
+const testMagnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567"
+
+func TestNewTask(t *testing.T) {
+	item := &scraper.Item{Name: "タイトル: 初回版", Brand: "Brand/Sub", Magnet: testMagnet}
+	task := NewTask(item, "/data/games", "galgame", "ggbases")
+	want := filepath.Join("/data/games", "Brand_Sub", "タイトル_ 初回版")
+	if task.SavePath != want {
+		t.Errorf("SavePath = %q, want %q", task.SavePath, want)
+	}
+	if task.Magnet != testMagnet || task.Category != "galgame" || len(task.Tags) != 1 {
+		t.Errorf("unexpected task %+v", task)
+	}
+
+	item.Titles = map[string]string{"zh": "标题"}
+	if task = NewTaskWithTitle(item, "/data/games", "galgame", []string{"zh", "ja"}); task.SavePath != filepath.Join("/data/games", "Brand_Sub", "标题") {
+		t.Errorf("SavePath with languages = %q", task.SavePath)
+	}
+	if err := (&Task{}).validate(); err != ErrEmptyTask {
+		t.Errorf("empty task err = %v", err)
+	}
+}
+
+func TestQBittorrent_Add(t *testing.T) {
+	var got map[string]string
+	var files int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.FormValue("username") != "admin" || r.FormValue("password") != "secret" {
+				_, _ = io.WriteString(w, "Fails.")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+			_, _ = io.WriteString(w, "Ok.")
+		case "/api/v2/torrents/add":
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "session" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Error(err)
+			}
+			got = map[string]string{}
+			for k, v := range r.MultipartForm.Value {
+				got[k] = v[0]
+			}
+			files = len(r.MultipartForm.File["torrents"])
+			if got["category"] == "rejected" {
+				_, _ = io.WriteString(w, "Fails.")
+				return
+			}
+			_, _ = io.WriteString(w, "Ok.")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	qb := &QBittorrent{Domain: srv.URL, Username: "admin", Password: "secret"}
+	err := qb.Add(&Task{Magnet: testMagnet, Category: "galgame", SavePath: "/data", Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["urls"] != testMagnet || got["category"] != "galgame" || got["savepath"] != "/data" || got["tags"] != "a,b" {
+		t.Errorf("unexpected form %v", got)
+	}
+
+	torrent := filepath.Join(t.TempDir(), "a.torrent")
+	if err = os.WriteFile(torrent, []byte("d4:infode"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = qb.Add(&Task{Torrent: torrent}); err != nil {
+		t.Fatal(err)
+	}
+	if files != 1 {
+		t.Errorf("expected torrent file upload, got %d", files)
+	}
+
+	if err = qb.Add(&Task{Magnet: testMagnet, Category: "rejected"}); err == nil {
+		t.Error("expected Fails. response to be an error")
+	}
+	// 调用方提供的 Client 没有 cookie jar 时也能保持会话
+	custom := &QBittorrent{Domain: srv.URL, Username: "admin", Password: "secret", Client: &http.Client{}}
+	if err = custom.Add(&Task{Magnet: testMagnet}); err != nil {
+		t.Errorf("client without jar: %v", err)
+	}
+
+	bad := &QBittorrent{Domain: srv.URL, Username: "admin", Password: "wrong"}
+	if err = bad.Add(&Task{Magnet: testMagnet}); err == nil {
+		t.Error("expected login failure")
+	}
+}
+
+func TestTransmission_Add(t *testing.T) {
+	var args map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(transmissionSessionHeader) != "sid" {
+			w.Header().Set(transmissionSessionHeader, "sid")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var req struct {
+			Method    string                 `json:"method"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Method != "torrent-add" {
+			_, _ = io.WriteString(w, `{"result":"method not recognized"}`)
+			return
+		}
+		args = req.Arguments
+		_, _ = io.WriteString(w, `{"result":"success","arguments":{"torrent-added":{"id":1}}}`)
+	}))
+	defer srv.Close()
+
+	tr := &Transmission{Domain: srv.URL}
+	err := tr.Add(&Task{Magnet: testMagnet, Category: "galgame", SavePath: "/data", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["filename"] != testMagnet || args["download-dir"] != "/data" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+	if labels, _ := args["labels"].([]interface{}); len(labels) != 2 {
+		t.Errorf("unexpected labels %v", args["labels"])
+	}
+}
+
+func TestAria2_Add(t *testing.T) {
+	var req struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Params[0] != "token:secret" {
+			_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":"1","error":{"code":1,"message":"Unauthorized"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":"1","result":"2089b05ecca3d829"}`)
+	}))
+	defer srv.Close()
+
+	a := &Aria2{Uri: srv.URL, Secret: "secret"}
+	if err := a.Add(&Task{Magnet: testMagnet, SavePath: "/data"}); err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "aria2.addUri" || len(req.Params) != 3 {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if opts, _ := req.Params[2].(map[string]interface{}); opts["dir"] != "/data" {
+		t.Errorf("unexpected options %v", req.Params[2])
+	}
+
+	a.Secret = "wrong"
+	if err := a.Add(&Task{Magnet: testMagnet}); err == nil {
+		t.Error("expected rpc error")
+	}
+}
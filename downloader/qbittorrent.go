@@ -0,0 +1,134 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// QBittorrent qBittorrent Web API 客户端
+type QBittorrent struct {
+	Domain   string // 例如 http://127.0.0.1:8080
+	Username string
+	Password string
+	Client   *http.Client // 没有 cookie jar 时会复制一份并加上，用于保存登录会话
+
+	once sync.Once
+}
+
+func (qb *QBittorrent) client() *http.Client {
+	qb.once.Do(func() {
+		if qb.Client == nil {
+			qb.Client = &http.Client{}
+		}
+		// 会话保存在 SID cookie 中，没有 jar 时每次请求都会被拒绝
+		if qb.Client.Jar == nil {
+			c := *qb.Client
+			c.Jar, _ = cookiejar.New(nil)
+			qb.Client = &c
+		}
+	})
+	return qb.Client
+}
+
+// Login 登录并保存会话 cookie
+func (qb *QBittorrent) Login() error {
+	form := url.Values{"username": {qb.Username}, "password": {qb.Password}}
+	req, err := http.NewRequest("POST", strings.TrimRight(qb.Domain, "/")+"/api/v2/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// qBittorrent 会校验 Referer 防止 CSRF
+	req.Header.Set("Referer", qb.Domain)
+	body, status, err := qb.do(req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || strings.TrimSpace(string(body)) != "Ok." {
+		return fmt.Errorf("qbittorrent 登录失败 status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+func (qb *QBittorrent) Add(task *Task) error {
+	if err := task.validate(); err != nil {
+		return err
+	}
+	body, status, err := qb.add(task)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusForbidden {
+		// 会话失效，重新登录后再试一次
+		if err = qb.Login(); err != nil {
+			return err
+		}
+		body, status, err = qb.add(task)
+		if err != nil {
+			return err
+		}
+	}
+	// 拒绝添加时同样返回 200，响应内容为 Fails.
+	if status != http.StatusOK || strings.TrimSpace(string(body)) == "Fails." {
+		return fmt.Errorf("qbittorrent 添加任务失败 status=%d body=%s", status, body)
+	}
+	return nil
+}
+
+func (qb *QBittorrent) add(task *Task) ([]byte, int, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	if task.Torrent != "" {
+		data, err := task.torrentData()
+		if err != nil {
+			return nil, 0, err
+		}
+		part, err := w.CreateFormFile("torrents", filepath.Base(task.Torrent))
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err = part.Write(data); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		_ = w.WriteField("urls", task.Magnet)
+	}
+	if task.Category != "" {
+		_ = w.WriteField("category", task.Category)
+	}
+	if task.SavePath != "" {
+		_ = w.WriteField("savepath", task.SavePath)
+	}
+	if len(task.Tags) > 0 {
+		_ = w.WriteField("tags", strings.Join(task.Tags, ","))
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimRight(qb.Domain, "/")+"/api/v2/torrents/add", buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Referer", qb.Domain)
+	return qb.do(req)
+}
+
+func (qb *QBittorrent) do(req *http.Request) ([]byte, int, error) {
+	res, err := qb.client().Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	return data, res.StatusCode, err
+}
@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Transmission Transmission RPC 客户端
+type Transmission struct {
+	Domain   string // 例如 http://127.0.0.1:9091
+	Username string
+	Password string
+	Client   *http.Client
+
+	lock      sync.Mutex
+	sessionID string
+}
+
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+func (tr *Transmission) Add(task *Task) error {
+	if err := task.validate(); err != nil {
+		return err
+	}
+	args := map[string]interface{}{}
+	if task.Torrent != "" {
+		data, err := task.torrentData()
+		if err != nil {
+			return err
+		}
+		args["metainfo"] = base64.StdEncoding.EncodeToString(data)
+	} else {
+		args["filename"] = task.Magnet
+	}
+	if task.SavePath != "" {
+		args["download-dir"] = task.SavePath
+	}
+	// Transmission 没有分类，分类与标签一并作为 labels
+	var labels []string
+	if task.Category != "" {
+		labels = append(labels, task.Category)
+	}
+	labels = append(labels, task.Tags...)
+	if len(labels) > 0 {
+		args["labels"] = labels
+	}
+
+	data, err := tr.call("torrent-add", args)
+	if err != nil {
+		return err
+	}
+	if result := gjson.GetBytes(data, "result").String(); result != "success" {
+		return fmt.Errorf("transmission 添加任务失败: %s", result)
+	}
+	return nil
+}
+
+func (tr *Transmission) call(method string, args interface{}) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{"method": method, "arguments": args})
+	if err != nil {
+		return nil, err
+	}
+	client := tr.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// 首次请求或 session id 过期时服务端返回 409，并在头部给出新的 session id
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", strings.TrimRight(tr.Domain, "/")+"/transmission/rpc", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if tr.Username != "" {
+			req.SetBasicAuth(tr.Username, tr.Password)
+		}
+		tr.lock.Lock()
+		req.Header.Set(transmissionSessionHeader, tr.sessionID)
+		tr.lock.Unlock()
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusConflict {
+			tr.lock.Lock()
+			tr.sessionID = res.Header.Get(transmissionSessionHeader)
+			tr.lock.Unlock()
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("transmission 请求失败 status=%d", res.StatusCode)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("transmission 请求失败: 无法获取 session id")
+}
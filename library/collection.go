@@ -0,0 +1,123 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"scraper/scraper"
+	"scraper/store"
+	"time"
+)
+
+// BangumiCollections 读写 bangumi 收藏，*scraper.Bangumi 实现了该接口
+type BangumiCollections interface {
+	Me() (string, error)
+	GetCollection(username, subjectID string) (*scraper.Collection, error)
+	UpdateCollection(c *scraper.Collection) error
+}
+
+// SyncResult 一次同步的统计
+type SyncResult struct {
+	Updated   int     // 已更新到 bangumi
+	Unchanged int     // 与 bangumi 一致
+	Conflicts int     // bangumi 上的修改比本地新，未覆盖
+	Failed    int     // 同步失败
+	Errors    []error // 失败原因
+}
+
+// CollectionSync 将本地库中的游玩状态同步到 bangumi 收藏
+//
+// 只同步上次同步后在本地修改过的条目。bangumi 上的收藏比本地修改更晚时视为冲突，
+// 除非设置了 Force，否则不会覆盖。标签和是否公开保留 bangumi 上的设置。
+type CollectionSync struct {
+	Store   *store.Store
+	Bangumi BangumiCollections
+	Force   bool // 冲突时仍以本地为准
+	DryRun  bool // 只统计不修改
+
+	now func() time.Time
+}
+
+func NewCollectionSync(s *store.Store, bangumi BangumiCollections) *CollectionSync {
+	return &CollectionSync{Store: s, Bangumi: bangumi}
+}
+
+// Sync 执行一次同步，单个条目失败不会中断同步
+func (cs *CollectionSync) Sync(ctx context.Context) (SyncResult, error) {
+	var result SyncResult
+	collections, err := cs.Store.UnsyncedCollections()
+	if err != nil || len(collections) == 0 {
+		return result, err
+	}
+	username, err := cs.Bangumi.Me()
+	if err != nil {
+		return result, err
+	}
+
+	for _, local := range collections {
+		if err = ctx.Err(); err != nil {
+			return result, err
+		}
+		updated, err := cs.syncOne(username, local)
+		switch {
+		case err == errConflict:
+			result.Conflicts++
+		case err != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("条目 %d (bangumi %s): %w", local.ItemID, local.BangumiID, err))
+		case updated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+	return result, nil
+}
+
+var errConflict = errors.New("bangumi 上的收藏更新")
+
+func (cs *CollectionSync) syncOne(username string, local store.Collection) (bool, error) {
+	want := &scraper.Collection{
+		SubjectID: local.BangumiID,
+		Type:      local.Type,
+		Rate:      local.Rate,
+		Comment:   local.Comment,
+	}
+	remote, err := cs.Bangumi.GetCollection(username, local.BangumiID)
+	if err != nil && !errors.Is(err, scraper.ErrNotCollected) {
+		return false, err
+	}
+	if remote != nil {
+		if want.Comment == "" {
+			want.Comment = remote.Comment
+		}
+		want.Tags, want.Private = remote.Tags, remote.Private
+		if remote.Equal(want) {
+			return false, cs.markSynced(local.ItemID)
+		}
+		if !cs.Force && remote.UpdatedAt.After(local.UpdatedAt) {
+			return false, errConflict
+		}
+	}
+	if cs.DryRun {
+		return true, nil
+	}
+	if err = cs.Bangumi.UpdateCollection(want); err != nil {
+		return false, err
+	}
+	return true, cs.markSynced(local.ItemID)
+}
+
+func (cs *CollectionSync) markSynced(itemID int64) error {
+	if cs.DryRun {
+		return nil
+	}
+	return cs.Store.MarkCollectionSynced(itemID, cs.clock())
+}
+
+func (cs *CollectionSync) clock() time.Time {
+	if cs.now != nil {
+		return cs.now()
+	}
+	return time.Now()
+}
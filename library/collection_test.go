@@ -0,0 +1,88 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"scraper/scraper"
+	"scraper/store"
+	"testing"
+	"time"
+)
+
+type fakeBangumi struct {
+	remote  map[string]*scraper.Collection
+	updates []scraper.Collection
+}
+
+func (f *fakeBangumi) Me() (string, error) { return "koi", nil }
+
+func (f *fakeBangumi) GetCollection(username, subjectID string) (*scraper.Collection, error) {
+	if c, ok := f.remote[subjectID]; ok {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, scraper.ErrNotCollected
+}
+
+func (f *fakeBangumi) UpdateCollection(c *scraper.Collection) error {
+	f.updates = append(f.updates, *c)
+	return nil
+}
+
+func TestCollectionSync_Sync(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	local := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	items := []*scraper.Item{
+		{Name: "new", Origin: "https://api.bgm.tv/v0/subjects/1"},
+		{Name: "same", Origin: "https://api.bgm.tv/v0/subjects/2"},
+		{Name: "conflict", Origin: "https://api.bgm.tv/v0/subjects/3"},
+		{Name: "changed", Origin: "https://www.getchu.com/soft.phtml?id=4", ExternalIDs: map[string]string{"bangumi": "4"}},
+		{Name: "no bangumi", Origin: "https://2dfan.org/subjects/5"},
+	}
+	for _, item := range items {
+		id, err := s.Upsert(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = s.SetCollection(store.Collection{ItemID: id, Type: scraper.CollectionDone, Rate: 8, UpdatedAt: local}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bangumi := &fakeBangumi{remote: map[string]*scraper.Collection{
+		"2": {SubjectID: "2", Type: scraper.CollectionDone, Rate: 8, UpdatedAt: local.Add(-time.Hour)},
+		"3": {SubjectID: "3", Type: scraper.CollectionWish, UpdatedAt: local.Add(time.Hour)},
+		"4": {SubjectID: "4", Type: scraper.CollectionDoing, Comment: "remote", Tags: []string{"t"}, UpdatedAt: local.Add(-time.Hour)},
+	}}
+	cs := NewCollectionSync(s, bangumi)
+	cs.now = func() time.Time { return local.Add(time.Minute) }
+	result, err := cs.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 2 || result.Unchanged != 1 || result.Conflicts != 1 || result.Failed != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(bangumi.updates) != 2 || bangumi.updates[0].SubjectID != "1" || bangumi.updates[1].Comment != "remote" ||
+		len(bangumi.updates[1].Tags) != 1 {
+		t.Errorf("unexpected updates %+v", bangumi.updates)
+	}
+
+	// 已同步的条目不会再次同步，冲突的条目仍然等待处理
+	result, err = cs.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Conflicts != 1 || result.Updated+result.Unchanged != 0 {
+		t.Errorf("unexpected second result %+v", result)
+	}
+	cs.Force = true
+	if result, _ = cs.Sync(context.Background()); result.Updated != 1 {
+		t.Errorf("force should overwrite conflicts, got %+v", result)
+	}
+}
@@ -0,0 +1,94 @@
+package library
+
+import (
+	"fmt"
+	"scraper/scraper"
+	"sync"
+)
+
+// Query 自动识别的查询条件，品牌和发售日可选
+type Query struct {
+	Title string
+	Brand string
+	Date  string
+}
+
+// Identified 自动识别的结果
+type Identified struct {
+	Item       *scraper.Item // 合并后的结果，没有可信的候选时为 nil
+	Merged     []Candidate   // 已抓取并合并的候选
+	Pending    []Candidate   // 置信度不足、需要人工确认的候选
+	Candidates []Candidate   // 所有数据源的全部候选
+	Errs       []error
+}
+
+// AutoIdentify 在所有数据源中搜索标题，每个数据源取最佳候选
+//
+// 置信度达到 AutoScore 的候选会被抓取并合并为一个 Item，其余的放入 Pending 交由人工确认。
+func (s *Scanner) AutoIdentify(q Query) *Identified {
+	match := s.identify(Entry{Title: q.Title, Brand: q.Brand, Date: q.Date})
+	result := &Identified{Candidates: match.Candidates, Errs: match.Errs}
+
+	// 候选已按置信度排序，每个数据源第一个即为最佳
+	seen := make(map[string]bool)
+	for _, c := range match.Candidates {
+		if seen[c.Source] {
+			continue
+		}
+		seen[c.Source] = true
+		if c.Score >= s.AutoScore {
+			result.Merged = append(result.Merged, c)
+		} else {
+			result.Pending = append(result.Pending, c)
+		}
+	}
+	if len(result.Merged) == 0 {
+		return result
+	}
+
+	items := make([]*scraper.Item, len(result.Merged))
+	errs := make([]error, len(result.Merged))
+	wait := sync.WaitGroup{}
+	for i, c := range result.Merged {
+		sc, ok := s.scraper(c.Source)
+		if !ok {
+			errs[i] = fmt.Errorf("数据源 %s 未注册", c.Source)
+			continue
+		}
+		wait.Add(1)
+		go func(i int, sc scraper.Scraper, uri string) {
+			defer wait.Done()
+			items[i], errs[i] = sc.GetItem(uri)
+		}(i, sc, c.Uri)
+	}
+	wait.Wait()
+
+	// 抓取失败的候选不参与合并
+	var merged []Candidate
+	var ok []*scraper.Item
+	for i, c := range result.Merged {
+		if errs[i] != nil || items[i] == nil {
+			if errs[i] != nil {
+				result.Errs = append(result.Errs, errs[i])
+			}
+			continue
+		}
+		if s.Taxonomy != nil {
+			s.Taxonomy.NormalizeItem(items[i])
+		}
+		merged = append(merged, c)
+		ok = append(ok, items[i])
+	}
+	result.Merged = merged
+	if len(ok) > 0 {
+		result.Item = scraper.Merge(ok...)
+	}
+	return result
+}
+
+func (s *Scanner) scraper(source string) (scraper.Scraper, bool) {
+	if sc, ok := s.Searchers[source].(scraper.Scraper); ok {
+		return sc, true
+	}
+	return scraper.Lookup(source)
+}
@@ -0,0 +1,71 @@
+package library
+
+import (
+	"errors"
+	"scraper/scraper"
+	"testing"
+)
+
+type fakeSource struct {
+	results []scraper.SearchResult
+	items   map[string]*scraper.Item
+}
+
+func (f *fakeSource) Search(keyword string) ([]scraper.SearchResult, error) {
+	return f.results, nil
+}
+
+func (f *fakeSource) GetItem(uri string) (*scraper.Item, error) {
+	if item, ok := f.items[uri]; ok {
+		return item, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestScanner_AutoIdentify(t *testing.T) {
+	s := &Scanner{
+		Searchers: map[string]scraper.Searcher{
+			"a": &fakeSource{
+				results: []scraper.SearchResult{
+					{Name: "サクラノ詩", Brand: "枕", Uri: "a/1"},
+					{Name: "サクラノ刻", Brand: "枕", Uri: "a/2"},
+				},
+				items: map[string]*scraper.Item{"a/1": {Name: "サクラノ詩", Brand: "枕", Genre: []string{"ADV"}}},
+			},
+			"b": &fakeSource{
+				results: []scraper.SearchResult{{Name: "サクラノ詩（初回版）", Uri: "b/1"}},
+				items: map[string]*scraper.Item{"b/1": {
+					Name:    "サクラノ詩（初回版）",
+					Story:   "story",
+					Genre:   []string{"ADV", "泣き"},
+					Preview: []string{"p1"},
+				}},
+			},
+			"c": &fakeSource{
+				results: []scraper.SearchResult{{Name: "サクラ大戦", Uri: "c/1"}},
+			},
+		},
+		MinScore:  0.3,
+		AutoScore: 0.8,
+	}
+
+	r := s.AutoIdentify(Query{Title: "サクラノ詩", Brand: "枕"})
+	if r.Item == nil {
+		t.Fatalf("expected merged item, errs: %v", r.Errs)
+	}
+	if len(r.Merged) != 2 {
+		t.Errorf("expected 2 merged candidates, got %+v", r.Merged)
+	}
+	if r.Item.Brand != "枕" || r.Item.Story != "story" || len(r.Item.Genre) != 2 || len(r.Item.Preview) != 1 {
+		t.Errorf("unexpected merged item %+v", r.Item)
+	}
+	if len(r.Pending) != 1 || r.Pending[0].Source != "c" {
+		t.Errorf("expected low-confidence c candidate pending, got %+v", r.Pending)
+	}
+
+	// 没有可信候选时不合并
+	r = s.AutoIdentify(Query{Title: "サクラ"})
+	if r.Item != nil || len(r.Merged) != 0 {
+		t.Errorf("expected no auto merge, got %+v", r.Merged)
+	}
+}
@@ -0,0 +1,72 @@
+package library
+
+import (
+	"path/filepath"
+	"regexp"
+	"scraper/tools"
+	"strings"
+)
+
+// Entry 从目录名或压缩包名中提取的信息
+type Entry struct {
+	Path  string       // 完整路径
+	Name  string       // 原始文件名（不含扩展名）
+	Title string       // 候选标题
+	Date  string       // 发售日，格式 2006-01-02
+	Brand string       // 品牌
+	Codes []tools.Code // 商品编号，例如 RJ123456
+}
+
+var (
+	// 常见的压缩包扩展名
+	archiveExts = map[string]bool{
+		".zip": true, ".rar": true, ".7z": true, ".tar": true, ".gz": true,
+		".iso": true, ".mdf": true, ".lzh": true,
+	}
+
+	bracketRe = regexp.MustCompile(`[\[【(（]([^\]】)）]*)[\]】)）]`)
+	dateRe    = regexp.MustCompile(`^(\d{2}|\d{4})[-./]?(\d{2})[-./]?(\d{2})$`)
+)
+
+// ParseName 解析 "[230630][Brand] Title (初回版)" 之类的名称
+func ParseName(path string) Entry {
+	base := filepath.Base(path)
+	if ext := strings.ToLower(filepath.Ext(base)); archiveExts[ext] {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	entry := Entry{Path: path, Name: base}
+
+	entry.Codes = tools.FindCodes(base)
+
+	title := base
+	for _, m := range bracketRe.FindAllStringSubmatch(base, -1) {
+		inner := strings.TrimSpace(m[1])
+		switch {
+		case entry.Date == "" && dateRe.MatchString(inner):
+			entry.Date = normalizeDate(inner)
+		case len(tools.FindCodes(inner)) > 0:
+		case entry.Brand == "" && strings.IndexAny(m[0], "[【") == 0:
+			// 方括号中第一个不是日期/编号的内容视为品牌
+			entry.Brand = inner
+		default:
+			// 圆括号一般是版本等附加说明，保留在原始名称中即可
+		}
+		title = strings.Replace(title, m[0], " ", 1)
+	}
+	title = tools.StripCodes(title)
+	title = strings.NewReplacer("_", " ").Replace(title)
+	entry.Title = strings.Join(strings.Fields(title), " ")
+	return entry
+}
+
+func normalizeDate(s string) string {
+	m := dateRe.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	year := m[1]
+	if len(year) == 2 {
+		year = "20" + year
+	}
+	return year + "-" + m[2] + "-" + m[3]
+}
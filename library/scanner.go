@@ -0,0 +1,160 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"scraper/scraper"
+	"scraper/tools"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Candidate 匹配候选
+type Candidate struct {
+	scraper.SearchResult
+	Score float64 // 置信度 0~1
+}
+
+// Match 一个目录/压缩包的匹配结果
+type Match struct {
+	Entry      Entry
+	Candidates []Candidate // 按置信度从高到低排序
+	Errs       []error     // 各数据源搜索时的错误
+}
+
+// Best 返回置信度最高的候选
+func (m *Match) Best() (Candidate, bool) {
+	if len(m.Candidates) == 0 {
+		return Candidate{}, false
+	}
+	return m.Candidates[0], true
+}
+
+// Scanner 扫描本地游戏库并到各数据源搜索匹配
+type Scanner struct {
+	Searchers     map[string]scraper.Searcher // 为空时使用所有已注册的数据源
+	MaxCandidates int                         // 每个条目保留的候选数量，0 表示不限制
+	MinScore      float64                     // 低于该置信度的候选会被丢弃
+	AutoScore     float64                     // 自动识别时达到该置信度的候选才会被自动合并
+	Taxonomy      *scraper.Taxonomy           // 不为空时在合并前统一各数据源的标签
+}
+
+// NewScanner 使用所有已注册的数据源创建扫描器
+func NewScanner() *Scanner {
+	return &Scanner{
+		Searchers:     scraper.Searchers(),
+		MaxCandidates: 5,
+		MinScore:      0.3,
+		AutoScore:     0.8,
+	}
+}
+
+// Walk 列出 root 下的游戏目录和压缩包（只看第一层）
+func Walk(root string) ([]Entry, error) {
+	files, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if !f.IsDir() && !archiveExts[strings.ToLower(filepath.Ext(f.Name()))] {
+			continue
+		}
+		entries = append(entries, ParseName(filepath.Join(root, f.Name())))
+	}
+	return entries, nil
+}
+
+// Scan 扫描 root 并为每个条目搜索匹配候选
+func (s *Scanner) Scan(root string) ([]Match, error) {
+	entries, err := Walk(root)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, 0, len(entries))
+	for _, entry := range entries {
+		matches = append(matches, s.Identify(entry))
+	}
+	return matches, nil
+}
+
+// Identify 在各数据源中并行搜索条目并按置信度排序
+func (s *Scanner) Identify(entry Entry) Match {
+	match := s.identify(entry)
+	if s.MaxCandidates > 0 && len(match.Candidates) > s.MaxCandidates {
+		match.Candidates = match.Candidates[:s.MaxCandidates]
+	}
+	return match
+}
+
+func (s *Scanner) identify(entry Entry) Match {
+	searchers := s.Searchers
+	if searchers == nil {
+		searchers = scraper.Searchers()
+	}
+	match := Match{Entry: entry}
+	// 名称中带有编号且对应的数据源已注册时，直接作为确定的候选
+	for _, code := range entry.Codes {
+		if _, ok := scraper.Lookup(code.Source); ok {
+			match.Candidates = append(match.Candidates, Candidate{
+				SearchResult: scraper.SearchResult{Source: code.Source, Uri: code.URL()},
+				Score:        1,
+			})
+		}
+	}
+	if entry.Title == "" {
+		return match
+	}
+
+	var lock sync.Mutex
+	wait := sync.WaitGroup{}
+	for name, searcher := range searchers {
+		wait.Add(1)
+		go func(name string, searcher scraper.Searcher) {
+			defer wait.Done()
+			results, err := searcher.Search(entry.Title)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				match.Errs = append(match.Errs, err)
+				return
+			}
+			for _, r := range results {
+				if r.Source == "" {
+					r.Source = name
+				}
+				score := Score(entry, r)
+				if score < s.MinScore {
+					continue
+				}
+				match.Candidates = append(match.Candidates, Candidate{SearchResult: r, Score: score})
+			}
+		}(name, searcher)
+	}
+	wait.Wait()
+
+	sort.SliceStable(match.Candidates, func(i, j int) bool {
+		return match.Candidates[i].Score > match.Candidates[j].Score
+	})
+	return match
+}
+
+// Score 计算条目与搜索结果的置信度
+//
+// 标题相似度占主要权重，品牌和发售日只有两边都有值时才参与计算。
+func Score(entry Entry, r scraper.SearchResult) float64 {
+	score, weight := 0.7*tools.TitleSimilarity(entry.Title, r.Name), 0.7
+	if entry.Brand != "" && r.Brand != "" {
+		score += 0.15 * tools.TitleSimilarity(entry.Brand, r.Brand)
+		weight += 0.15
+	}
+	if entry.Date != "" && r.ReleaseDate != "" {
+		score += 0.15 * dateSimilarity(entry.Date, r.ReleaseDate)
+		weight += 0.15
+	}
+	return score / weight
+}
@@ -0,0 +1,89 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"scraper/scraper"
+	"scraper/tools"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Entry
+	}{
+		{"[230630][Brand] Title (初回版)", Entry{Title: "Title", Date: "2023-06-30", Brand: "Brand"}},
+		{"【ゆずソフト】天使☆騒々 RE-BOOT!.rar", Entry{Title: "天使☆騒々 RE-BOOT!", Brand: "ゆずソフト"}},
+		{"RJ01012345 Some_Game", Entry{Title: "Some Game", Codes: []tools.Code{{Source: "dlsite", ID: "RJ01012345"}}}},
+		{"[2019-01-25][rj123456] 作品", Entry{Title: "作品", Date: "2019-01-25", Codes: []tools.Code{{Source: "dlsite", ID: "RJ123456"}}}},
+		{"getchu_1219845 タイトル", Entry{Title: "タイトル", Codes: []tools.Code{{Source: "getchu", ID: "1219845"}}}},
+	}
+	for _, c := range cases {
+		got := ParseName(c.in)
+		if got.Title != c.want.Title || got.Date != c.want.Date || got.Brand != c.want.Brand || len(got.Codes) != len(c.want.Codes) {
+			t.Errorf("ParseName(%q) = %+v, want %+v", c.in, got, c.want)
+			continue
+		}
+		for i := range got.Codes {
+			if got.Codes[i] != c.want.Codes[i] {
+				t.Errorf("ParseName(%q) codes = %v, want %v", c.in, got.Codes, c.want.Codes)
+			}
+		}
+	}
+}
+
+type fakeSearcher []scraper.SearchResult
+
+func (f fakeSearcher) Search(keyword string) ([]scraper.SearchResult, error) {
+	return f, nil
+}
+
+func TestScanner_Scan(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"[230630][Brand] Title", ".hidden"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"Other Game.zip", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Scanner{
+		Searchers: map[string]scraper.Searcher{
+			"a": fakeSearcher{
+				{Source: "a", Name: "Title", Brand: "Brand", ReleaseDate: "2023-06-30", Uri: "a/1"},
+				{Source: "a", Name: "Unrelated", Uri: "a/2"},
+			},
+			"b": fakeSearcher{
+				{Source: "b", Name: "Title 2", Brand: "Other", ReleaseDate: "2021-01-01", Uri: "b/1"},
+			},
+		},
+		MinScore: 0.3,
+	}
+	matches, err := s.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(matches))
+	}
+	var m Match
+	for _, match := range matches {
+		if match.Entry.Title == "Title" {
+			m = match
+		}
+	}
+	best, ok := m.Best()
+	if !ok || best.Uri != "a/1" || best.Score < 0.99 {
+		t.Errorf("unexpected best candidate %+v", best)
+	}
+	for _, c := range m.Candidates {
+		if c.Uri == "a/2" {
+			t.Errorf("unrelated result should be filtered: %+v", c)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package library
+
+import (
+	"regexp"
+	"strings"
+)
+
+var dateDigitsRe = regexp.MustCompile(`(\d{4})\D*(\d{1,2})?\D*(\d{1,2})?`)
+
+// dateSimilarity 同一天为 1，同月为 0.6，同年为 0.2
+func dateSimilarity(a, b string) float64 {
+	ma, mb := dateDigitsRe.FindStringSubmatch(a), dateDigitsRe.FindStringSubmatch(b)
+	if ma == nil || mb == nil || ma[1] != mb[1] {
+		return 0
+	}
+	if ma[2] == "" || mb[2] == "" || strings.TrimLeft(ma[2], "0") != strings.TrimLeft(mb[2], "0") {
+		return 0.2
+	}
+	if ma[3] == "" || mb[3] == "" || strings.TrimLeft(ma[3], "0") != strings.TrimLeft(mb[3], "0") {
+		return 0.6
+	}
+	return 1
+}
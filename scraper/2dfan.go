@@ -45,16 +45,36 @@ func (tdf *TwoDFan) GetItem(uri string) (*Item, error) {
 		return nil, err
 	}
 	item := &Item{Origin: uri}
+	item.AddExternalCodes(uri)
 	// 获取名称
 	item.Name, err = tdf.GetItemName(root)
 	if err != nil {
 		fmt.Println("获取名称失败 url:", uri, "err:", err)
 	}
+	// 标题为原名，又名 中通常有中文译名
+	item.OriginalTitle = item.Name
+	item.AddTitle(LangJa, item.Name)
+	aliases, err := tdf.GetItemAliases(root)
+	if err != nil {
+		fmt.Println("获取别名失败 url:", uri, "err:", err)
+	}
+	for _, alias := range aliases {
+		if GuessLanguage(alias) == LangZh {
+			item.AddTitle(LangZh, alias)
+		} else {
+			item.AddAlias(alias)
+		}
+	}
 	// 获取品牌
 	item.Brand, err = tdf.GetItemBrand(root)
 	if err != nil {
 		fmt.Println("获取品牌失败 url:", uri, "err:", err)
 	}
+	// 获取品牌页面
+	item.BrandUri, err = tdf.GetItemBrandUri(root)
+	if err != nil {
+		fmt.Println("获取品牌页面失败 url:", uri, "err:", err)
+	}
 	// 获取发售日
 	item.ReleaseDate, err = tdf.GetItemReleaseDate(root)
 	if err != nil {
@@ -102,6 +122,39 @@ func (tdf *TwoDFan) GetItemBrand(node *goquery.Document) (string, error) {
 	return brand, nil
 }
 
+// GetItemBrandUri 获取品牌标签的链接
+func (tdf *TwoDFan) GetItemBrandUri(node *goquery.Document) (string, error) {
+	link := ""
+	node.Find(`div[class="media-body control-group"] p.tags`).Each(func(i int, selection *goquery.Selection) {
+		if link == "" && strings.Contains(selection.Text(), "品牌") {
+			if href, ok := selection.Find("a").First().Attr("href"); ok {
+				link = tools.AbsImage(tdf.Domain, href)
+			}
+		}
+	})
+	return link, nil
+}
+
+// GetItemAliases 获取 又名 中的名称
+func (tdf *TwoDFan) GetItemAliases(node *goquery.Document) ([]string, error) {
+	var aliases []string
+	node.Find(`div[class="media-body control-group"] p.tags`).Each(func(i int, selection *goquery.Selection) {
+		text := strings.TrimSpace(selection.Text())
+		if !strings.HasPrefix(text, "又名") {
+			return
+		}
+		text = strings.TrimLeft(strings.TrimPrefix(text, "又名"), "：: ")
+		for _, alias := range strings.FieldsFunc(text, func(r rune) bool {
+			return r == '、' || r == '/' || r == '，' || r == '\n'
+		}) {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				aliases = append(aliases, alias)
+			}
+		}
+	})
+	return aliases, nil
+}
+
 func (tdf *TwoDFan) GetItemReleaseDate(node *goquery.Document) (string, error) {
 	date := ""
 	node.Find(`div[class="media-body control-group"] p.tags`).Each(func(i int, selection *goquery.Selection) {
@@ -182,29 +235,41 @@ func (tdf *TwoDFan) GetOtherInfo(id string, node *goquery.Document, item *Item)
 					case strings.Contains(str, "人物介绍"):
 						item.Character = []Character{}
 						c := Character{}
+						// flush 保存上一个角色，介绍中的身高、三围等一并识别
+						flush := func() {
+							if c.Name != "" {
+								c.Introduction = strings.TrimSpace(information.String())
+								parseCharacterProfile(&c, c.Introduction)
+								item.Character = append(item.Character, c)
+							}
+							c = Character{}
+							information.Reset()
+						}
 						for {
 							selection = selection.Next()
-							if selection.Is("h4") {
+							if selection.Length() == 0 || selection.Is("h4") {
+								flush()
 								item.Story = story.String()
 								return
 							}
 
-							if selection.Is("strong") {
-								if !selection.Prev().Is("h4") {
-									item.Character = append(item.Character, c)
-									information.Reset()
-								}
-
-								c.Name = selection.Find("strong").First().Text()
+							// 只有加粗文字的一行是角色名
+							if strong := selfOrFind(selection, "strong"); strong.Length() > 0 &&
+								strings.TrimSpace(strong.Text()) == strings.TrimSpace(selection.Text()) {
+								flush()
+								name, voiceActor := splitCharaName(strong.Text())
+								name, reading := splitReading(name)
+								c.Name, c.VoiceActor = name, voiceActor
+								c.AddAlias(reading)
 								continue
 							}
-							if selection.Is("img") {
-								c.Avatar, _ = selection.Find("img").First().Attr("src")
-
-								selection = selection.Next()
-								image, ok := selection.Find("img").First().Attr("src")
-								if ok {
-									c.Images = []string{image}
+							if img := selfOrFind(selection, "img"); img.Length() > 0 {
+								if image, ok := img.Attr("src"); ok {
+									if c.Avatar == "" {
+										c.Avatar = image
+									} else {
+										c.Images = append(c.Images, image)
+									}
 								}
 								continue
 							}
@@ -237,6 +302,93 @@ func (tdf *TwoDFan) GetOtherInfo(id string, node *goquery.Document, item *Item)
 	})
 }
 
+// selfOrFind 元素本身匹配时返回自身，否则返回第一个匹配的子元素
+func selfOrFind(s *goquery.Selection, selector string) *goquery.Selection {
+	if s.Is(selector) {
+		return s
+	}
+	return s.Find(selector).First()
+}
+
+func (tdf *TwoDFan) Search(keyword string) ([]SearchResult, error) {
+	data, err := tdf.DoReq("GET", fmt.Sprintf(tdf.SearchUri, url.QueryEscape(keyword)), nil)
+	if err != nil {
+		return nil, err
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return tdf.parseSearchResults(root), nil
+}
+
+// parseSearchResults 解析作品列表，搜索结果和品牌标签页使用同样的列表
+func (tdf *TwoDFan) parseSearchResults(root *goquery.Document) []SearchResult {
+	var results []SearchResult
+	root.Find("ul.media-list li.media").Each(func(i int, li *goquery.Selection) {
+		a := li.Find("h4.media-heading a").First()
+		href, ok := a.Attr("href")
+		if !ok {
+			return
+		}
+		result := SearchResult{
+			Source: "2dfan",
+			Name:   strings.TrimSpace(a.Text()),
+			Uri:    tools.AbsImage(tdf.Domain, href),
+		}
+		li.Find("p.tags").Each(func(i int, p *goquery.Selection) {
+			switch {
+			case strings.Contains(p.Text(), "品牌"):
+				result.Brand = strings.TrimSpace(p.Find("a").First().Text())
+			case strings.Contains(p.Text(), "发售日期"):
+				result.ReleaseDate = strings.TrimSpace(strings.Replace(p.Text(), "发售日期：", "", 1))
+			}
+		})
+		results = append(results, result)
+	})
+	return results
+}
+
+// GetBrand 抓取品牌标签页中的作品，uri 为作品页品牌标签的链接
+func (tdf *TwoDFan) GetBrand(uri string) (*Brand, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	brand := &Brand{}
+	path := strings.Split(strings.TrimSuffix(u.Path, "/"), "/")
+	brand.AddSourceID("2dfan", path[len(path)-1])
+	works, err := collectWorks(func(page int) ([]Release, error) {
+		q := u.Query()
+		q.Set("page", fmt.Sprint(page))
+		pageURL := *u
+		pageURL.RawQuery = q.Encode()
+		data, err := tdf.DoReq("GET", pageURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+		if err != nil {
+			return nil, err
+		}
+		if brand.Name == "" {
+			brand.Name = strings.TrimSpace(root.Find("div.navbar h3").First().Text())
+		}
+		return searchResultsToReleases(tdf.parseSearchResults(root)), nil
+	})
+	brand.Works = works
+	for _, w := range works {
+		if w.Brand != "" {
+			// 页面标题作为别名，需要在改名之后添加，否则与当前名称相同会被忽略
+			old := brand.Name
+			brand.Name = w.Brand
+			brand.AddAlias(old)
+			break
+		}
+	}
+	return brand, err
+}
+
 func init() {
 	headers := make(map[string]string)
 	headers["User-Agent"] = defaultUserAgent
@@ -248,4 +400,5 @@ func init() {
 		SearchUri: twoDFanSearchUri,
 		Headers:   headers,
 	}
+	Register("2dfan", TwoDFanScraper)
 }
@@ -6,16 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"github.com/tidwall/gjson"
+	"os"
+	"regexp"
 	"scraper/tools"
 	"strings"
 	"sync"
 )
 
 var (
-	bangumiToken     = "HQ0i2YxrfBErFThGbAeXk3d9JYqqdv4pewIyDqYc"
 	bangumiUserAgent = "dokidokikoi/meta-scraper (https://github.com/dokidokikoi/meta-scraper)"
 
-	BangumiDomain = "https://api.bgm.tv/"
+	BangumiDomain    = "https://api.bgm.tv/"
+	BangumiSearchUri = "https://api.bgm.tv/v0/search/subjects?limit=10"
+	BangumiItemUri   = "https://api.bgm.tv/v0/subjects/%s"
+	BangumiPersonUri = "https://api.bgm.tv/v0/persons/%s"
+
+	bangumiPersonIDRe = regexp.MustCompile(`persons?/(\d+)`)
 )
 
 type Bangumi struct {
@@ -23,6 +29,8 @@ type Bangumi struct {
 	Domain    string
 	SearchUri string
 	Headers   map[string]string
+	Token     TokenSource   // 为空时不带授权访问，只能获取公开内容
+	Types     []SubjectType // 搜索的条目类型，为空时只搜索游戏
 }
 
 var BangumiScraper *Bangumi
@@ -33,10 +41,32 @@ func (b *Bangumi) DoReq(method, uri string, body interface{}) ([]byte, error) {
 		return nil, err
 	}
 
-	data, _, err = tools.MakeRequest(method, uri, b.Proxy, bytes.NewBuffer(data), b.Headers, nil)
+	headers, err := b.requestHeaders()
+	if err != nil {
+		return nil, err
+	}
+	data, _, err = tools.MakeRequest(method, uri, b.Proxy, bytes.NewBuffer(data), headers, nil)
 	return data, err
 }
 
+// requestHeaders 复制 Headers 并加上当前的 access token
+func (b *Bangumi) requestHeaders() (map[string]string, error) {
+	headers := make(map[string]string, len(b.Headers)+1)
+	for k, v := range b.Headers {
+		headers[k] = v
+	}
+	if b.Token != nil {
+		token, err := b.Token.Token()
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+	}
+	return headers, nil
+}
+
 func (b *Bangumi) GetItem(uri string) (*Item, error) {
 	data, err := b.DoReq("GET", uri, nil)
 	if err != nil {
@@ -44,11 +74,19 @@ func (b *Bangumi) GetItem(uri string) (*Item, error) {
 	}
 
 	item := &Item{Origin: uri}
+	item.AddExternalID("bangumi", gjson.GetBytes(data, "id").String())
 	// 获取名称
 	item.Name, err = b.GetItemName(data)
 	if err != nil {
 		fmt.Println("获取名称失败 url:", uri, "err:", err)
 	}
+	// 获取 infobox
+	item.Infobox, err = b.GetItemInfobox(data)
+	if err != nil {
+		fmt.Println("获取 infobox 失败 url:", uri, "err:", err)
+	}
+	// 获取原名、各语言名称和别名
+	b.GetItemTitles(item, data)
 	// 获取预览图
 	item.Preview, err = b.GetItemPreview(data)
 	if err != nil {
@@ -84,9 +122,41 @@ func (b *Bangumi) GetItem(uri string) (*Item, error) {
 	if err != nil {
 		fmt.Println("获取故事简介失败 url:", uri, "err:", err)
 	}
+	// 获取各类型特有的信息
+	if err = b.GetItemExtension(item, data); err != nil {
+		fmt.Println("获取条目类型信息失败 url:", uri, "err:", err)
+	}
+	// 获取外部编号
+	ids, err := b.GetItemExternalIDs(data)
+	if err != nil {
+		fmt.Println("获取外部编号失败 url:", uri, "err:", err)
+	}
+	for source, id := range ids {
+		item.AddExternalID(source, id)
+	}
+	id := gjson.GetBytes(data, "id").String()
+	// 获取关联人物
+	persons, err := b.GetItemPersons(id)
+	if err != nil {
+		fmt.Println("获取关联人物失败 url:", uri, "err:", err)
+	}
+	// 获取品牌页面
+	item.BrandUri, err = b.GetItemBrandUri(persons)
+	if err != nil {
+		fmt.Println("获取品牌页面失败 url:", uri, "err:", err)
+	}
+	// 获取制作人员
+	item.Staff, err = b.GetItemStaff(data, persons)
+	if err != nil {
+		fmt.Println("获取制作人员失败 url:", uri, "err:", err)
+	}
+	// 获取关联条目
+	item.Relations, err = b.GetItemRelations(id)
+	if err != nil {
+		fmt.Println("获取关联条目失败 url:", uri, "err:", err)
+	}
 	// 获取角色信息
 	var errs []error
-	id := gjson.GetBytes(data, "id").String()
 	item.Character, errs = b.GetItemCharacter(id)
 	if errs != nil {
 		fmt.Println("获取角色信息失败 url:", uri, "err:", errs)
@@ -98,44 +168,280 @@ func (b *Bangumi) GetItemName(data []byte) (string, error) {
 	return gjson.GetBytes(data, "name").String(), nil
 }
 
+// bangumiTitleKeys infobox 中名称的键或别名的小标题 -> 语言
+var bangumiTitleKeys = map[string]string{
+	"中文名":   LangZh,
+	"简体中文名": LangZh,
+	"日文名":   LangJa,
+	"英文名":   LangEn,
+}
+
+// GetItemTitles name 为原名，name_cn 为中文名，infobox 中的中文名、别名等作为其它名称
+func (b *Bangumi) GetItemTitles(item *Item, data []byte) {
+	name := strings.TrimSpace(gjson.GetBytes(data, "name").String())
+	item.OriginalTitle = name
+	// bangumi 的原名大多为日文，只有汉字的日文名会被猜成中文
+	if lang := GuessLanguage(name); lang == LangEn {
+		item.AddTitle(lang, name)
+	} else {
+		item.AddTitle(LangJa, name)
+	}
+	item.AddTitle(LangZh, gjson.GetBytes(data, "name_cn").String())
+	for _, entry := range parseBangumiInfobox(gjson.GetBytes(data, "infobox")) {
+		lang, isTitle := bangumiTitleKeys[entry.Key]
+		if !isTitle && entry.Key != "别名" {
+			continue
+		}
+		for _, v := range entry.Values {
+			if l, ok := bangumiTitleKeys[v.Label]; ok {
+				item.AddTitle(l, v.Value)
+			} else {
+				item.AddTitle(lang, v.Value)
+			}
+		}
+	}
+}
+
 func (b *Bangumi) GetItemPreview(data []byte) ([]string, error) {
 	return []string{gjson.GetBytes(data, "images.large").String()}, nil
 }
 
+// GetItemGenre 游戏取 游戏类型，其它类型的条目取对应的 infobox 键，没有时使用 platform
 func (b *Bangumi) GetItemGenre(data []byte) ([]string, error) {
-	for _, info := range gjson.GetBytes(data, "infobox").Array() {
-		if info.Get("key").String() == "游戏类型" {
-			return []string{info.Get("value").String()}, nil
-		}
+	t := subjectType(data)
+	if values := infoboxLookup(data, bangumiSubjectKeys[t].genre, false); len(values) > 0 {
+		return values, nil
 	}
-	return nil, errors.New("未匹配游戏类型")
+	if t == SubjectGame {
+		return nil, errors.New("未匹配游戏类型")
+	}
+	if platform := gjson.GetBytes(data, "platform").String(); platform != "" {
+		return []string{platform}, nil
+	}
+	return nil, fmt.Errorf("未匹配%s条目类别", t)
 }
 
+// GetItemBrand 游戏取开发商，动画取动画制作，书籍取出版社，音乐取厂牌
 func (b *Bangumi) GetItemBrand(data []byte) (string, error) {
-	for _, info := range gjson.GetBytes(data, "infobox").Array() {
-		if strings.Contains(info.Get("key").String(), "开发") {
-			return info.Get("value").String(), nil
-		}
+	t := subjectType(data)
+	if values := infoboxLookup(data, bangumiSubjectKeys[t].brand, t == SubjectGame); len(values) > 0 {
+		return strings.Join(values, "、"), nil
+	}
+	if t == SubjectGame {
+		return "", errors.New("未匹配游戏品牌")
 	}
-	return "", errors.New("未匹配游戏品牌")
+	return "", fmt.Errorf("未匹配%s条目品牌", t)
 }
 
+// GetItemReleaseDate 取对应类型的发售日或放送开始日期，没有时使用条目的 date
 func (b *Bangumi) GetItemReleaseDate(data []byte) (string, error) {
+	t := subjectType(data)
+	if value := firstValue(infoboxLookup(data, bangumiSubjectKeys[t].date, false)); value != "" {
+		return value, nil
+	}
+	if date := gjson.GetBytes(data, "date").String(); date != "" {
+		return date, nil
+	}
+	if t == SubjectGame {
+		return "", errors.New("未匹配游戏发行日期")
+	}
+	return "", fmt.Errorf("未匹配%s条目发售日", t)
+}
+
+func (b *Bangumi) GetItemLink(data []byte) (string, error) {
+	infobox, _ := b.GetItemInfobox(data)
+	if website := infobox.Website(); website != "" {
+		return website, nil
+	}
+	if t := subjectType(data); t != SubjectGame {
+		return "", fmt.Errorf("未匹配%s条目官网链接", t)
+	}
+	return "", errors.New("未匹配游戏官网链接")
+}
+
+// GetItemExtension 设置条目类型，并按类型填充 Item.Anime、Item.Book 或 Item.Music
+func (b *Bangumi) GetItemExtension(item *Item, data []byte) error {
+	t := subjectType(data)
+	if t != SubjectGame {
+		item.Kind = t.String()
+	}
+	switch t {
+	case SubjectAnime, SubjectReal, SubjectMusic:
+		episodes, err := b.GetItemEpisodes(gjson.GetBytes(data, "id").String())
+		if t == SubjectMusic {
+			item.Music, _ = b.GetItemMusic(data, episodes)
+		} else {
+			item.Anime, _ = b.GetItemAnime(data, episodes)
+		}
+		return err
+	case SubjectBook:
+		var err error
+		item.Book, err = b.GetItemBook(data)
+		return err
+	}
+	return nil
+}
+
+// infoboxValues 取出 infobox 一项的所有值，多值项为 [{"k": ..., "v": ...}] 形式
+func infoboxValues(info gjson.Result) []string {
+	var values []string
+	for _, v := range parseBangumiInfoboxValue("", info.Get("value")) {
+		if v.Value = strings.TrimSpace(v.Value); v.Value != "" {
+			values = append(values, v.Value)
+		}
+	}
+	return values
+}
+
+// GetItemInfobox 按原顺序保留完整的 infobox
+func (b *Bangumi) GetItemInfobox(data []byte) (Infobox, error) {
+	return parseBangumiInfobox(gjson.GetBytes(data, "infobox")), nil
+}
+
+// GetItemPersons 获取条目的关联人物和公司
+func (b *Bangumi) GetItemPersons(id string) ([]byte, error) {
+	return b.DoReq("GET", fmt.Sprintf(BangumiItemUri+"/persons", id), nil)
+}
+
+// GetItemBrandUri 从关联人物中找到开发（或发行）的公司，返回其人物页面
+func (b *Bangumi) GetItemBrandUri(persons []byte) (string, error) {
+	for _, relation := range []string{"开发", "发行"} {
+		for _, p := range gjson.ParseBytes(persons).Array() {
+			if p.Get("relation").String() == relation {
+				return fmt.Sprintf(BangumiPersonUri, p.Get("id").String()), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// GetItemStaff 从关联人物中取出个人和组合，infobox 中有而关联人物中没有的职务也会补上
+func (b *Bangumi) GetItemStaff(data, persons []byte) ([]Staff, error) {
+	item := &Item{}
+	for _, p := range gjson.ParseBytes(persons).Array() {
+		// type 2 为公司
+		if p.Get("type").Int() == 2 {
+			continue
+		}
+		item.AddStaff(Staff{
+			Role:      p.Get("relation").String(),
+			Name:      p.Get("name").String(),
+			SourceIDs: map[string]string{"bangumi": p.Get("id").String()},
+		})
+	}
 	for _, info := range gjson.GetBytes(data, "infobox").Array() {
-		if info.Get("key").String() == "发行日期" {
-			return info.Get("value").String(), nil
+		key := info.Get("key").String()
+		if !isStaffRole(key) {
+			continue
+		}
+		for _, v := range infoboxValues(info) {
+			for _, name := range splitNames(v) {
+				item.AddStaff(Staff{Role: key, Name: name})
+			}
 		}
 	}
-	return "", errors.New("未匹配游戏发行日期")
+	return item.Staff, nil
 }
 
-func (b *Bangumi) GetItemLink(data []byte) (string, error) {
+// GetItemRelations 获取续集、前传、FD 等关联条目
+func (b *Bangumi) GetItemRelations(id string) ([]Relation, error) {
+	data, err := b.DoReq("GET", fmt.Sprintf(BangumiItemUri+"/subjects", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseBangumiRelations(data), nil
+}
+
+func parseBangumiRelations(data []byte) []Relation {
+	item := &Item{}
+	for _, s := range gjson.ParseBytes(data).Array() {
+		id := s.Get("id").String()
+		name := s.Get("name").String()
+		if name == "" {
+			name = s.Get("name_cn").String()
+		}
+		label := s.Get("relation").String()
+		item.AddRelation(Relation{
+			Kind:   NormalizeRelation(label),
+			Label:  label,
+			Source: "bangumi",
+			ID:     id,
+			Name:   name,
+			Uri:    fmt.Sprintf(BangumiItemUri, id),
+		})
+	}
+	return item.Relations
+}
+
+// GetBrand 抓取公司（人物）及其参与的条目，uri 可以是网页或 API 的人物地址
+func (b *Bangumi) GetBrand(uri string) (*Brand, error) {
+	m := bangumiPersonIDRe.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoBrandSource, uri)
+	}
+	person, err := b.DoReq("GET", fmt.Sprintf(BangumiPersonUri, m[1]), nil)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := b.DoReq("GET", fmt.Sprintf(BangumiPersonUri+"/subjects", m[1]), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseBangumiBrand(m[1], person, subjects), nil
+}
+
+func parseBangumiBrand(id string, person, subjects []byte) *Brand {
+	brand := &Brand{Name: gjson.GetBytes(person, "name").String()}
+	brand.AddSourceID("bangumi", id)
+	for _, info := range gjson.GetBytes(person, "infobox").Array() {
+		key := info.Get("key").String()
+		switch {
+		case strings.Contains(key, "中文名"), strings.Contains(key, "别名"):
+			for _, v := range infoboxValues(info) {
+				brand.AddAlias(v)
+			}
+		case key == "website", strings.Contains(key, "官网"), strings.Contains(key, "主页"):
+			if values := infoboxValues(info); brand.Link == "" && len(values) > 0 {
+				brand.Link = values[0]
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range gjson.ParseBytes(subjects).Array() {
+		sid := s.Get("id").String()
+		// 只保留游戏条目，旧版接口不返回 type 时全部保留
+		if sid == "" || seen[sid] || (s.Get("type").Exists() && s.Get("type").Int() != 4) {
+			continue
+		}
+		seen[sid] = true
+		name := s.Get("name").String()
+		if name == "" {
+			name = s.Get("name_cn").String()
+		}
+		brand.Works = append(brand.Works, Release{
+			Source: "bangumi",
+			Name:   name,
+			Brand:  brand.Name,
+			Uri:    fmt.Sprintf(BangumiItemUri, sid),
+		})
+	}
+	return brand
+}
+
+// GetItemExternalIDs 从 infobox 的链接中识别其它数据源的编号
+func (b *Bangumi) GetItemExternalIDs(data []byte) (map[string]string, error) {
+	ids := make(map[string]string)
 	for _, info := range gjson.GetBytes(data, "infobox").Array() {
-		if info.Get("key").String() == "website" {
-			return info.Get("value").String(), nil
+		for _, v := range infoboxValues(info) {
+			for _, code := range tools.FindCodes(v) {
+				if _, ok := ids[code.Source]; !ok {
+					ids[code.Source] = code.ID
+				}
+			}
 		}
 	}
-	return "", errors.New("未匹配游戏官网链接")
+	return ids, nil
 }
 
 func (b *Bangumi) GetItemStory(data []byte) (string, error) {
@@ -144,45 +450,116 @@ func (b *Bangumi) GetItemStory(data []byte) (string, error) {
 
 func (b Bangumi) GetItemCharacter(id string) ([]Character, []error) {
 	var errs []error
-	var characters []Character
 	data, err := b.DoReq("GET", fmt.Sprintf("https://api.bgm.tv/v0/subjects/%s/characters", id), nil)
 	if err != nil {
 		return nil, append(errs, fmt.Errorf("发送获取角色请求失败 err %v", err))
 	}
 
+	list := gjson.ParseBytes(data).Array()
+	if len(list) > 10 {
+		list = list[:10]
+	}
+	// 按角色列表的顺序返回
+	results := make([]*Character, len(list))
 	var lock sync.Mutex
 	wait := sync.WaitGroup{}
-	for i, c := range gjson.ParseBytes(data).Array() {
-		if i >= 10 {
-			break
-		}
-		cid := c.Get("id").String()
-
-		if cid != "" {
-			wait.Add(1)
-			go func(cid string) {
-				data, err = b.DoReq("GET", fmt.Sprintf("https://api.bgm.tv/v0/characters/%s", cid), nil)
-				if err != nil {
-					errs = append(errs, err)
-					return
-				}
-
+	for i, c := range list {
+		if c.Get("id").String() == "" {
+			continue
+		}
+		wait.Add(1)
+		go func(i int, c gjson.Result) {
+			defer wait.Done()
+			data, err := b.DoReq("GET", fmt.Sprintf("https://api.bgm.tv/v0/characters/%s", c.Get("id").String()), nil)
+			if err != nil {
 				lock.Lock()
-				characters = append(characters, Character{
-					Name:         gjson.GetBytes(data, "name").String(),
-					Introduction: gjson.GetBytes(data, "summary").String(),
-					Avatar:       gjson.GetBytes(data, "images.large").String(),
-				})
+				errs = append(errs, err)
 				lock.Unlock()
-				wait.Done()
-			}(cid)
-		}
-
+				return
+			}
+			character := parseBangumiCharacter(c, data)
+			results[i] = &character
+		}(i, c)
 	}
 	wait.Wait()
+
+	var characters []Character
+	for _, c := range results {
+		if c != nil {
+			characters = append(characters, *c)
+		}
+	}
 	return characters, errs
 }
 
+// bangumiBloodTypes 角色接口中 blood_type 的取值
+var bangumiBloodTypes = map[int64]string{1: "A", 2: "B", 3: "AB", 4: "O"}
+
+// parseBangumiCharacter entry 为条目角色列表中的一项，只有这里给出角色定位和声优，data 为角色详情
+func parseBangumiCharacter(entry gjson.Result, data []byte) Character {
+	detail := gjson.ParseBytes(data)
+	c := Character{
+		Name:         detail.Get("name").String(),
+		Introduction: detail.Get("summary").String(),
+		Avatar:       detail.Get("images.large").String(),
+		Role:         NormalizeCharacterRole(entry.Get("relation").String()),
+		BloodType:    bangumiBloodTypes[detail.Get("blood_type").Int()],
+	}
+	if bangumiMaskRe.MatchString(c.Introduction) {
+		c.Spoiler = SpoilerMinor
+	}
+	c.AddSourceID("bangumi", detail.Get("id").String())
+	var actors []string
+	for _, actor := range entry.Get("actors").Array() {
+		actors = append(actors, actor.Get("name").String())
+	}
+	c.VoiceActor = strings.Join(actors, "、")
+
+	switch detail.Get("gender").String() {
+	case "female":
+		c.Gender = "女"
+	case "male":
+		c.Gender = "男"
+	}
+	if month, day := detail.Get("birth_mon").Int(), detail.Get("birth_day").Int(); month > 0 && day > 0 {
+		c.Birthday = fmt.Sprintf("%02d-%02d", month, day)
+		if year := detail.Get("birth_year").Int(); year > 0 {
+			c.Birthday = fmt.Sprintf("%d-%s", year, c.Birthday)
+		}
+	}
+
+	for _, info := range detail.Get("infobox").Array() {
+		key := info.Get("key").String()
+		values := infoboxValues(info)
+		value := strings.Join(values, "、")
+		switch key {
+		case "简体中文名", "别名":
+			for _, v := range values {
+				c.AddAlias(v)
+			}
+		case "性别":
+			if c.Gender == "" {
+				c.Gender = value
+			}
+		case "生日":
+			if c.Birthday == "" {
+				c.Birthday = parseBirthday(value)
+			}
+		case "血型":
+			if c.BloodType == "" {
+				c.BloodType = strings.TrimSuffix(strings.ToUpper(value), "型")
+			}
+		case "身高":
+			c.Height = value
+		case "BWH", "三围":
+			c.Measurements = value
+		default:
+			c.SetTrait(key, value)
+		}
+	}
+	return c
+}
+
 func (b *Bangumi) GetItemTags(data []byte) ([]Tag, error) {
 	var tags []TagItem
 	for _, t := range gjson.GetBytes(data, "tags").Array() {
@@ -190,20 +567,75 @@ func (b *Bangumi) GetItemTags(data []byte) ([]Tag, error) {
 		tags = append(tags, TagItem{
 			Identity: name,
 			Name:     name,
+			Count:    int(t.Get("count").Int()),
 		})
 	}
+	setConfidence(tags)
 
 	return []Tag{{Item: tags}}, nil
 }
 
+func (b *Bangumi) Search(keyword string) ([]SearchResult, error) {
+	types := []int{int(SubjectGame)}
+	if len(b.Types) > 0 {
+		types = types[:0]
+		for _, t := range b.Types {
+			types = append(types, int(t))
+		}
+	}
+	body := map[string]interface{}{
+		"keyword": keyword,
+		"sort":    "match",
+		"filter": map[string]interface{}{
+			"type": types,
+			"nsfw": true,
+		},
+	}
+	data, err := b.DoReq("POST", b.SearchUri, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.parseSearchResults(data), nil
+}
+
+// parseSearchResults 解析搜索接口的结果
+//
+// 搜索结果一般不带 infobox，此时没有品牌，只有带 infobox 时才取品牌。
+func (b *Bangumi) parseSearchResults(data []byte) []SearchResult {
+	var results []SearchResult
+	for _, subject := range gjson.GetBytes(data, "data").Array() {
+		var brand string
+		if subject.Get("infobox").Exists() {
+			brand, _ = b.GetItemBrand([]byte(subject.Raw))
+		}
+		name := subject.Get("name").String()
+		if name == "" {
+			name = subject.Get("name_cn").String()
+		}
+		results = append(results, SearchResult{
+			Source:      "bangumi",
+			Name:        name,
+			Brand:       brand,
+			ReleaseDate: subject.Get("date").String(),
+			Uri:         fmt.Sprintf(BangumiItemUri, subject.Get("id").String()),
+		})
+	}
+	return results
+}
+
 func init() {
 	headers := make(map[string]string)
 	headers["User-Agent"] = bangumiUserAgent
-	headers["Authorization"] = fmt.Sprintf("Bearer %s", bangumiToken)
 	BangumiScraper = &Bangumi{
 		Proxy:     defaultProxy,
 		Domain:    BangumiDomain,
-		SearchUri: "",
+		SearchUri: BangumiSearchUri,
 		Headers:   headers,
 	}
+	// 个人令牌通过环境变量配置，多用户时使用 BangumiOAuth.TokenSource
+	if token := os.Getenv("BANGUMI_TOKEN"); token != "" {
+		BangumiScraper.Token = StaticToken(token)
+	}
+	Register("bangumi", BangumiScraper)
 }
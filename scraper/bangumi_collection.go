@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"scraper/tools"
+	"strings"
+	"time"
+)
+
+// CollectionType bangumi 收藏状态
+type CollectionType int
+
+const (
+	CollectionWish    CollectionType = 1 // 想玩
+	CollectionDone    CollectionType = 2 // 玩过
+	CollectionDoing   CollectionType = 3 // 在玩
+	CollectionOnHold  CollectionType = 4 // 搁置
+	CollectionDropped CollectionType = 5 // 抛弃
+)
+
+var collectionTypeNames = map[CollectionType]string{
+	CollectionWish:    "wish",
+	CollectionDone:    "collect",
+	CollectionDoing:   "doing",
+	CollectionOnHold:  "on_hold",
+	CollectionDropped: "dropped",
+}
+
+func (t CollectionType) String() string {
+	if name, ok := collectionTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("CollectionType(%d)", int(t))
+}
+
+// ParseCollectionType 解析 wish、collect、doing、on_hold、dropped
+func ParseCollectionType(s string) (CollectionType, error) {
+	for t, name := range collectionTypeNames {
+		if strings.EqualFold(s, name) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的收藏状态 %q", s)
+}
+
+var (
+	// ErrNotCollected 用户没有收藏该条目
+	ErrNotCollected = errors.New("未收藏该条目")
+	// ErrUnauthorized 没有配置 token 或 token 已失效
+	ErrUnauthorized = errors.New("bangumi 授权失败")
+)
+
+// Collection 用户对一个条目的收藏
+type Collection struct {
+	SubjectID string         // 条目编号
+	Type      CollectionType // 收藏状态
+	Rate      int            // 评分 1-10，0 为未评分
+	Comment   string         // 吐槽
+	Tags      []string       // 标签
+	Private   bool           // 仅自己可见
+	UpdatedAt time.Time      // 最后修改时间，只在读取时返回
+}
+
+// Equal 收藏状态、评分和吐槽是否相同
+func (c *Collection) Equal(other *Collection) bool {
+	return c.Type == other.Type && c.Rate == other.Rate && strings.TrimSpace(c.Comment) == strings.TrimSpace(other.Comment)
+}
+
+// doAPI 请求 Domain 下的接口，body 为 nil 时不发送请求体，返回非 2xx 状态码时转为错误
+func (b *Bangumi) doAPI(method, path string, body interface{}) ([]byte, int, error) {
+	var reader io.Reader
+	headers, err := b.requestHeaders()
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		reader = bytes.NewBuffer(data)
+		headers["Content-Type"] = "application/json"
+	}
+	data, status, err := tools.MakeRequest(method, strings.TrimSuffix(b.Domain, "/")+path, b.Proxy, reader, headers, nil)
+	if err != nil {
+		return nil, status, err
+	}
+	switch {
+	case status == http.StatusUnauthorized:
+		return data, status, ErrUnauthorized
+	case status >= http.StatusBadRequest:
+		msg := gjson.GetBytes(data, "description").String()
+		if msg == "" {
+			msg = gjson.GetBytes(data, "title").String()
+		}
+		return data, status, fmt.Errorf("bangumi 返回状态码 %d: %s", status, msg)
+	}
+	return data, status, nil
+}
+
+// Me 返回当前 token 对应的用户名
+func (b *Bangumi) Me() (string, error) {
+	data, _, err := b.doAPI("GET", "/v0/me", nil)
+	if err != nil {
+		return "", err
+	}
+	return gjson.GetBytes(data, "username").String(), nil
+}
+
+// GetCollection 获取用户对条目的收藏，未收藏时返回 ErrNotCollected
+func (b *Bangumi) GetCollection(username, subjectID string) (*Collection, error) {
+	data, status, err := b.doAPI("GET", fmt.Sprintf("/v0/users/%s/collections/%s", username, subjectID), nil)
+	if status == http.StatusNotFound {
+		return nil, ErrNotCollected
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Collection{
+		SubjectID: gjson.GetBytes(data, "subject_id").String(),
+		Type:      CollectionType(gjson.GetBytes(data, "type").Int()),
+		Rate:      int(gjson.GetBytes(data, "rate").Int()),
+		Comment:   gjson.GetBytes(data, "comment").String(),
+		Private:   gjson.GetBytes(data, "private").Bool(),
+	}
+	for _, tag := range gjson.GetBytes(data, "tags").Array() {
+		c.Tags = append(c.Tags, tag.String())
+	}
+	c.UpdatedAt, _ = time.Parse(time.RFC3339, gjson.GetBytes(data, "updated_at").String())
+	return c, nil
+}
+
+// UpdateCollection 新增或修改当前用户对条目的收藏
+func (b *Bangumi) UpdateCollection(c *Collection) error {
+	if _, ok := collectionTypeNames[c.Type]; !ok {
+		return fmt.Errorf("未知的收藏状态 %d", int(c.Type))
+	}
+	if c.Rate < 0 || c.Rate > 10 {
+		return fmt.Errorf("评分 %d 超出范围 0-10", c.Rate)
+	}
+	body := map[string]interface{}{
+		"type":    c.Type,
+		"rate":    c.Rate,
+		"comment": c.Comment,
+		"private": c.Private,
+	}
+	if c.Tags != nil {
+		body["tags"] = c.Tags
+	}
+	_, _, err := b.doAPI("POST", fmt.Sprintf("/v0/users/-/collections/%s", c.SubjectID), body)
+	return err
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBangumi_Collection(t *testing.T) {
+	var updated map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"username": "koi"}`))
+	})
+	mux.HandleFunc("/v0/users/koi/collections/226254", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"subject_id": 226254, "type": 2, "rate": 9, "comment": "神作",
+			"tags": ["枕"], "private": false, "updated_at": "2023-07-01T10:00:00+08:00"}`))
+	})
+	mux.HandleFunc("/v0/users/koi/collections/1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"title": "Not Found"}`))
+	})
+	mux.HandleFunc("/v0/users/-/collections/226254", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&updated)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	b := &Bangumi{Domain: server.URL + "/", Headers: map[string]string{"Authorization": "Bearer token"}}
+	username, err := b.Me()
+	if err != nil || username != "koi" {
+		t.Fatalf("Me() = %q, %v", username, err)
+	}
+	c, err := b.GetCollection(username, "226254")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Type != CollectionDone || c.Rate != 9 || c.Comment != "神作" || len(c.Tags) != 1 || c.UpdatedAt.IsZero() {
+		t.Errorf("unexpected collection %+v", c)
+	}
+	if _, err = b.GetCollection(username, "1"); !errors.Is(err, ErrNotCollected) {
+		t.Errorf("expected ErrNotCollected, got %v", err)
+	}
+
+	c.Type, c.Rate = CollectionDoing, 10
+	if err = b.UpdateCollection(c); err != nil {
+		t.Fatal(err)
+	}
+	if updated["type"] != float64(CollectionDoing) || updated["rate"] != float64(10) || updated["comment"] != "神作" {
+		t.Errorf("unexpected update body %v", updated)
+	}
+	if err = b.UpdateCollection(&Collection{SubjectID: "226254", Type: 9}); err == nil {
+		t.Errorf("expected error for invalid type")
+	}
+
+	b.Headers = nil
+	if _, err = b.Me(); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestParseCollectionType(t *testing.T) {
+	for _, typ := range []CollectionType{CollectionWish, CollectionDone, CollectionDoing, CollectionOnHold, CollectionDropped} {
+		if got, err := ParseCollectionType(typ.String()); err != nil || got != typ {
+			t.Errorf("ParseCollectionType(%q) = %v, %v", typ.String(), got, err)
+		}
+	}
+	if _, err := ParseCollectionType("played"); err == nil {
+		t.Errorf("expected error for unknown type")
+	}
+}
@@ -0,0 +1,359 @@
+package scraper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"scraper/tools"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	BangumiAuthorizeUri = "https://bgm.tv/oauth/authorize"
+	BangumiTokenUri     = "https://bgm.tv/oauth/access_token"
+)
+
+// ErrTokenNotFound 令牌存储中没有该用户的令牌，需要先授权
+var ErrTokenNotFound = errors.New("未找到令牌，请先授权")
+
+// TokenSource 为每次请求提供 access token
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// StaticToken 固定的 access token，例如 bangumi 的个人令牌
+type StaticToken string
+
+func (t StaticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+// OAuthToken OAuth 令牌
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	UserID       string    `json:"user_id"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// expiresWithin 令牌是否会在 d 内过期，没有过期时间的令牌视为不过期
+func (t *OAuthToken) expiresWithin(now time.Time, d time.Duration) bool {
+	return !t.Expiry.IsZero() && !now.Add(d).Before(t.Expiry)
+}
+
+// TokenStore 令牌存储，key 通常为本服务中的用户名
+type TokenStore interface {
+	Load(key string) (*OAuthToken, error) // 不存在时返回 ErrTokenNotFound
+	Save(key string, token *OAuthToken) error
+}
+
+// MemoryTokenStore 保存在内存中的令牌
+type MemoryTokenStore struct {
+	lock   sync.Mutex
+	tokens map[string]OAuthToken
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]OAuthToken)}
+}
+
+func (s *MemoryTokenStore) Load(key string) (*OAuthToken, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &t, nil
+}
+
+func (s *MemoryTokenStore) Save(key string, token *OAuthToken) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens[key] = *token
+	return nil
+}
+
+// FileTokenStore 每个用户一个 JSON 文件，文件权限为 0600
+type FileTokenStore struct {
+	Dir string
+}
+
+func (s *FileTokenStore) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key)+".json")
+}
+
+func (s *FileTokenStore) Load(key string) (*OAuthToken, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &OAuthToken{}
+	if err = json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("解析令牌文件失败: %w", err)
+	}
+	return t, nil
+}
+
+func (s *FileTokenStore) Save(key string, token *OAuthToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免写到一半时留下损坏的令牌
+	tmp := s.path(key) + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(key))
+}
+
+// BangumiOAuth bangumi OAuth 授权码流程
+type BangumiOAuth struct {
+	ClientID      string
+	ClientSecret  string
+	RedirectURI   string // 回调地址，Authorize 会在该地址的端口上监听
+	AuthorizeUri  string
+	TokenUri      string
+	Proxy         string
+	Store         TokenStore
+	RefreshBefore time.Duration // 令牌在过期前多久刷新
+
+	now func() time.Time
+}
+
+func NewBangumiOAuth(clientID, clientSecret, redirectURI string, store TokenStore) *BangumiOAuth {
+	return &BangumiOAuth{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		RedirectURI:   redirectURI,
+		AuthorizeUri:  BangumiAuthorizeUri,
+		TokenUri:      BangumiTokenUri,
+		Proxy:         defaultProxy,
+		Store:         store,
+		RefreshBefore: time.Hour,
+	}
+}
+
+// AuthCodeURL 返回用户需要在浏览器中打开的授权页面
+func (o *BangumiOAuth) AuthCodeURL(state string) string {
+	q := url.Values{}
+	q.Set("client_id", o.ClientID)
+	q.Set("response_type", "code")
+	q.Set("redirect_uri", o.RedirectURI)
+	q.Set("state", state)
+	return o.AuthorizeUri + "?" + q.Encode()
+}
+
+// Exchange 用授权码换取令牌
+func (o *BangumiOAuth) Exchange(code string) (*OAuthToken, error) {
+	return o.requestToken(url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+	})
+}
+
+// Refresh 用 refresh token 换取新令牌
+func (o *BangumiOAuth) Refresh(token *OAuthToken) (*OAuthToken, error) {
+	if token.RefreshToken == "" {
+		return nil, errors.New("令牌没有 refresh token")
+	}
+	refreshed, err := o.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 响应中没有新的 refresh token 时沿用原来的
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func (o *BangumiOAuth) requestToken(form url.Values) (*OAuthToken, error) {
+	form.Set("client_id", o.ClientID)
+	form.Set("client_secret", o.ClientSecret)
+	form.Set("redirect_uri", o.RedirectURI)
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+		"User-Agent":   bangumiUserAgent,
+	}
+	data, status, err := tools.MakeRequest("POST", o.TokenUri, o.Proxy, strings.NewReader(form.Encode()), headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status >= http.StatusBadRequest {
+		return nil, fmt.Errorf("获取令牌失败 status = %d: %s %s", status,
+			gjson.GetBytes(data, "error").String(), gjson.GetBytes(data, "error_description").String())
+	}
+	t := &OAuthToken{
+		AccessToken:  gjson.GetBytes(data, "access_token").String(),
+		RefreshToken: gjson.GetBytes(data, "refresh_token").String(),
+		TokenType:    gjson.GetBytes(data, "token_type").String(),
+		UserID:       gjson.GetBytes(data, "user_id").String(),
+	}
+	if t.AccessToken == "" {
+		return nil, errors.New("获取令牌失败: 响应中没有 access_token")
+	}
+	if expiresIn := gjson.GetBytes(data, "expires_in").Int(); expiresIn > 0 {
+		t.Expiry = o.clock().Add(time.Duration(expiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// Authorize 在回调地址上监听，通过 open 让用户打开授权页面，收到授权码后换取令牌并保存到 key 下
+//
+// open 通常是打开浏览器或打印链接。ctx 取消时放弃等待。
+func (o *BangumiOAuth) Authorize(ctx context.Context, key string, open func(authURL string) error) (*OAuthToken, error) {
+	redirect, err := url.Parse(o.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, fmt.Errorf("监听回调地址失败: %w", err)
+	}
+	state, err := randomState()
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		// state 不匹配的请求可能是浏览器预取、favicon 等，不结束授权流程
+		if q.Get("state") != state {
+			http.Error(w, "回调的 state 不匹配", http.StatusBadRequest)
+			return
+		}
+		var res result
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("用户拒绝授权: %s", q.Get("error"))
+		case q.Get("code") == "":
+			res.err = errors.New("回调中没有授权码")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = fmt.Fprintln(w, "授权成功，可以关闭此页面")
+		}
+		select {
+		case done <- res:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	defer func() { _ = server.Close() }()
+
+	if err = open(o.AuthCodeURL(state)); err != nil {
+		return nil, err
+	}
+	var res result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-done:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	token, err := o.Exchange(res.code)
+	if err != nil {
+		return nil, err
+	}
+	if err = o.Store.Save(key, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// TokenSource 返回 key 对应用户的令牌来源，令牌快过期时自动刷新并保存
+func (o *BangumiOAuth) TokenSource(key string) TokenSource {
+	return &oauthTokenSource{oauth: o, key: key}
+}
+
+type oauthTokenSource struct {
+	oauth *BangumiOAuth
+	key   string
+	lock  sync.Mutex
+	token *OAuthToken
+}
+
+func (s *oauthTokenSource) Token() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.token == nil {
+		token, err := s.oauth.Store.Load(s.key)
+		if err != nil {
+			return "", err
+		}
+		s.token = token
+	}
+	now := s.oauth.clock()
+	if !s.token.expiresWithin(now, s.oauth.RefreshBefore) {
+		return s.token.AccessToken, nil
+	}
+	token, err := s.oauth.Refresh(s.token)
+	if err != nil {
+		// 刷新失败但旧令牌还没过期时继续使用
+		if !s.token.expiresWithin(now, 0) {
+			return s.token.AccessToken, nil
+		}
+		return "", fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	// 保存失败时新令牌仍然可用，旧的 refresh token 可能已经失效，不能继续使用旧令牌
+	s.token = token
+	if err = s.oauth.Store.Save(s.key, token); err != nil {
+		fmt.Println("保存令牌失败 key:", s.key, "err:", err)
+	}
+	return token.AccessToken, nil
+}
+
+func (o *BangumiOAuth) clock() time.Time {
+	if o.now != nil {
+		return o.now()
+	}
+	return time.Now()
+}
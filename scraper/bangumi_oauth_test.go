@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// oauthServer 模拟 bangumi 的授权页面和令牌接口
+func oauthServer(refreshes *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "id" || q.Get("response_type") != "code" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=good&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			if r.PostForm.Get("code") != "good" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token": "a1", "refresh_token": "r1", "expires_in": 604800, "token_type": "Bearer", "user_id": 7}`))
+		case "refresh_token":
+			n := atomic.AddInt32(refreshes, 1)
+			if r.PostForm.Get("refresh_token") == "keep" {
+				_, _ = fmt.Fprintf(w, `{"access_token": "a%d", "expires_in": 604800, "token_type": "Bearer"}`, n+1)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"access_token": "a%d", "refresh_token": "r%d", "expires_in": 604800, "token_type": "Bearer", "user_id": 7}`, n+1, n+1)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestBangumiOAuth_Authorize(t *testing.T) {
+	var refreshes int32
+	server := oauthServer(&refreshes)
+	defer server.Close()
+
+	store := &FileTokenStore{Dir: t.TempDir()}
+	o := NewBangumiOAuth("id", "secret", "http://"+freeAddr(t)+"/callback", store)
+	o.AuthorizeUri, o.TokenUri, o.Proxy = server.URL+"/oauth/authorize", server.URL+"/oauth/access_token", ""
+	now := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	o.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// 模拟用户在浏览器中打开授权页面并同意
+	token, err := o.Authorize(ctx, "koi", func(authURL string) error {
+		go func() {
+			// state 不匹配的请求不影响授权
+			resp, err := http.Get(o.RedirectURI + "?code=bad&state=wrong")
+			if err != nil || resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("mismatched state should get 400, got %v, %v", resp, err)
+			}
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			resp, err = http.Get(authURL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "a1" || token.UserID != "7" || !token.Expiry.Equal(now.Add(7*24*time.Hour)) {
+		t.Errorf("unexpected token %+v", token)
+	}
+	saved, err := store.Load("koi")
+	if err != nil || saved.RefreshToken != "r1" {
+		t.Fatalf("token not saved: %+v, %v", saved, err)
+	}
+
+	source := o.TokenSource("koi")
+	if got, err := source.Token(); err != nil || got != "a1" {
+		t.Errorf("Token() = %q, %v", got, err)
+	}
+	// 快过期时刷新并保存新令牌
+	now = now.Add(7*24*time.Hour - 30*time.Minute)
+	if got, err := source.Token(); err != nil || got != "a2" {
+		t.Errorf("Token() after expiry = %q, %v", got, err)
+	}
+	if saved, _ = store.Load("koi"); saved.RefreshToken != "r2" || refreshes != 1 {
+		t.Errorf("refreshed token not saved: %+v", saved)
+	}
+
+	if _, err = o.TokenSource("other").Token(); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestBangumiOAuth_Errors(t *testing.T) {
+	var refreshes int32
+	server := oauthServer(&refreshes)
+	defer server.Close()
+
+	o := NewBangumiOAuth("id", "wrong", "http://127.0.0.1/callback", NewMemoryTokenStore())
+	o.TokenUri, o.Proxy = server.URL+"/oauth/access_token", ""
+	if _, err := o.Exchange("good"); err == nil {
+		t.Errorf("expected error for invalid client")
+	}
+
+	// 刷新失败且令牌已过期时返回 ErrUnauthorized
+	now := time.Now()
+	_ = o.Store.Save("koi", &OAuthToken{AccessToken: "old", RefreshToken: "r", Expiry: now.Add(-time.Minute)})
+	if _, err := o.TokenSource("koi").Token(); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	// 刷新失败但令牌还没过期时继续使用旧令牌
+	_ = o.Store.Save("koi", &OAuthToken{AccessToken: "old", RefreshToken: "r", Expiry: now.Add(time.Minute)})
+	if got, err := o.TokenSource("koi").Token(); err != nil || got != "old" {
+		t.Errorf("Token() = %q, %v", got, err)
+	}
+}
+
+// failingTokenStore 读取正常但保存总是失败
+type failingTokenStore struct {
+	*MemoryTokenStore
+}
+
+func (s failingTokenStore) Save(key string, token *OAuthToken) error {
+	return errors.New("disk full")
+}
+
+func TestBangumiOAuth_RefreshSaveError(t *testing.T) {
+	var refreshes int32
+	server := oauthServer(&refreshes)
+	defer server.Close()
+
+	store := failingTokenStore{NewMemoryTokenStore()}
+	now := time.Now()
+	_ = store.MemoryTokenStore.Save("koi", &OAuthToken{AccessToken: "old", RefreshToken: "keep", Expiry: now.Add(time.Minute)})
+	o := NewBangumiOAuth("id", "secret", "http://127.0.0.1/callback", store)
+	o.TokenUri, o.Proxy = server.URL+"/oauth/access_token", ""
+
+	// 保存失败时仍然使用刷新后的令牌，响应中没有 refresh token 时沿用原来的
+	source := o.TokenSource("koi").(*oauthTokenSource)
+	if got, err := source.Token(); err != nil || got != "a2" {
+		t.Errorf("Token() = %q, %v", got, err)
+	}
+	if source.token.RefreshToken != "keep" {
+		t.Errorf("refresh token should be kept, got %+v", source.token)
+	}
+	if got, err := source.Token(); err != nil || got != "a2" || refreshes != 1 {
+		t.Errorf("refreshed token should be cached, got %q, %v, %d refreshes", got, err, refreshes)
+	}
+}
+
+func TestBangumi_TokenSource(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"username": "koi"}`))
+	}))
+	defer server.Close()
+
+	b := &Bangumi{Domain: server.URL, Headers: map[string]string{"User-Agent": "test"}, Token: StaticToken("t")}
+	if _, err := b.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer t" || len(b.Headers) != 1 {
+		t.Errorf("unexpected Authorization %q, headers %v", auth, b.Headers)
+	}
+}
@@ -0,0 +1,204 @@
+package scraper
+
+import (
+	"fmt"
+	"github.com/tidwall/gjson"
+	"strconv"
+	"strings"
+)
+
+// SubjectType bangumi 条目类型
+type SubjectType int
+
+const (
+	SubjectBook  SubjectType = 1 // 书籍
+	SubjectAnime SubjectType = 2 // 动画
+	SubjectMusic SubjectType = 3 // 音乐
+	SubjectGame  SubjectType = 4 // 游戏
+	SubjectReal  SubjectType = 6 // 三次元
+)
+
+var subjectTypeNames = map[SubjectType]string{
+	SubjectBook:  "book",
+	SubjectAnime: "anime",
+	SubjectMusic: "music",
+	SubjectGame:  "game",
+	SubjectReal:  "real",
+}
+
+// String 返回 Item.Kind 使用的名称
+func (t SubjectType) String() string {
+	if name, ok := subjectTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("SubjectType(%d)", int(t))
+}
+
+// subjectKeys 各类型条目中 品牌、发售日、类别 对应的 infobox 键，按优先级排列
+type subjectKeys struct {
+	brand []string
+	date  []string
+	genre []string
+}
+
+var bangumiSubjectKeys = map[SubjectType]subjectKeys{
+	SubjectGame:  {brand: []string{"开发"}, date: []string{"发行日期"}, genre: []string{"游戏类型"}},
+	SubjectAnime: {brand: []string{"动画制作", "製作"}, date: []string{"放送开始", "上映年度", "发售日"}},
+	SubjectBook:  {brand: []string{"出版社"}, date: []string{"发售日", "开始"}, genre: []string{"连载杂志"}},
+	SubjectMusic: {brand: []string{"厂牌", "艺术家"}, date: []string{"发售日期", "发售日"}},
+	SubjectReal:  {brand: []string{"制作", "电视台"}, date: []string{"开始", "上映日", "首播"}},
+}
+
+// subjectType 条目数据中的类型，没有时视为游戏
+func subjectType(data []byte) SubjectType {
+	if t := gjson.GetBytes(data, "type"); t.Exists() {
+		return SubjectType(t.Int())
+	}
+	return SubjectGame
+}
+
+// infoboxLookup 按顺序查找第一个有值的 infobox 键，contains 为 true 时键包含即可
+func infoboxLookup(data []byte, keys []string, contains bool) []string {
+	return parseBangumiInfobox(gjson.GetBytes(data, "infobox")).lookup(keys, contains)
+}
+
+// Episode 动画、三次元的一集，或音乐的一首曲目
+type Episode struct {
+	ID       string
+	Type     int     // 0 本篇 1 SP 2 OP 3 ED 4 预告 5 MAD 6 其他
+	Sort     float64 // 在条目中的序号
+	Disc     int     // 音乐曲目所在的碟片
+	Name     string
+	NameCN   string
+	AirDate  string
+	Duration string
+}
+
+// AnimeInfo 动画和三次元条目的信息
+type AnimeInfo struct {
+	Platform string    // TV、OVA、剧场版 等
+	Episodes int       // 总集数
+	EndDate  string    // 放送结束
+	List     []Episode // 剧集列表
+}
+
+// BookInfo 书籍条目的信息
+type BookInfo struct {
+	Platform  string   // 小说、漫画 等
+	ISBN      string   // 国际标准书号
+	Volumes   int      // 卷数
+	Pages     int      // 页数
+	Authors   []string // 作者
+	Publisher string   // 出版社
+	Series    bool     // 是否为系列
+}
+
+// MusicInfo 音乐条目的信息
+type MusicInfo struct {
+	Artists []string  // 艺术家
+	Label   string    // 厂牌
+	Catalog string    // 碟片编号
+	Discs   int       // 碟片数
+	Tracks  []Episode // 曲目
+}
+
+// GetItemEpisodes 获取条目的所有剧集或曲目
+func (b *Bangumi) GetItemEpisodes(id string) ([]Episode, error) {
+	var episodes []Episode
+	const limit = 200
+	for offset := 0; ; offset += limit {
+		data, err := b.DoReq("GET", fmt.Sprintf("%s/v0/episodes?subject_id=%s&limit=%d&offset=%d", strings.TrimSuffix(b.Domain, "/"), id, limit, offset), nil)
+		if err != nil {
+			return episodes, err
+		}
+		page := parseBangumiEpisodes(data)
+		episodes = append(episodes, page...)
+		if len(page) < limit || int64(len(episodes)) >= gjson.GetBytes(data, "total").Int() {
+			return episodes, nil
+		}
+	}
+}
+
+func parseBangumiEpisodes(data []byte) []Episode {
+	var episodes []Episode
+	for _, e := range gjson.GetBytes(data, "data").Array() {
+		episodes = append(episodes, Episode{
+			ID:       e.Get("id").String(),
+			Type:     int(e.Get("type").Int()),
+			Sort:     e.Get("sort").Float(),
+			Disc:     int(e.Get("disc").Int()),
+			Name:     e.Get("name").String(),
+			NameCN:   e.Get("name_cn").String(),
+			AirDate:  e.Get("airdate").String(),
+			Duration: e.Get("duration").String(),
+		})
+	}
+	return episodes
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimFunc(s, func(r rune) bool { return r < '0' || r > '9' }))
+	return n
+}
+
+// GetItemAnime 动画和三次元条目的集数等信息，episodes 为 GetItemEpisodes 的结果
+func (b *Bangumi) GetItemAnime(data []byte, episodes []Episode) (*AnimeInfo, error) {
+	info := &AnimeInfo{
+		Platform: gjson.GetBytes(data, "platform").String(),
+		Episodes: int(gjson.GetBytes(data, "total_episodes").Int()),
+		EndDate:  firstValue(infoboxLookup(data, []string{"放送结束", "结束"}, false)),
+		List:     episodes,
+	}
+	if info.Episodes == 0 {
+		info.Episodes = int(gjson.GetBytes(data, "eps").Int())
+	}
+	if info.Episodes == 0 {
+		info.Episodes = atoi(firstValue(infoboxLookup(data, []string{"话数", "集数"}, false)))
+	}
+	return info, nil
+}
+
+// GetItemBook 书籍条目的 ISBN、卷数等信息
+func (b *Bangumi) GetItemBook(data []byte) (*BookInfo, error) {
+	info := &BookInfo{
+		Platform:  gjson.GetBytes(data, "platform").String(),
+		ISBN:      firstValue(infoboxLookup(data, []string{"ISBN"}, false)),
+		Volumes:   int(gjson.GetBytes(data, "volumes").Int()),
+		Pages:     atoi(firstValue(infoboxLookup(data, []string{"页数"}, false))),
+		Publisher: firstValue(infoboxLookup(data, []string{"出版社"}, false)),
+		Series:    gjson.GetBytes(data, "series").Bool(),
+	}
+	for _, v := range infoboxLookup(data, []string{"作者", "原作"}, false) {
+		info.Authors = append(info.Authors, splitNames(v)...)
+	}
+	if info.Volumes == 0 {
+		info.Volumes = atoi(firstValue(infoboxLookup(data, []string{"册数", "卷数"}, false)))
+	}
+	return info, nil
+}
+
+// GetItemMusic 音乐条目的艺术家、厂牌和曲目，tracks 为 GetItemEpisodes 的结果
+func (b *Bangumi) GetItemMusic(data []byte, tracks []Episode) (*MusicInfo, error) {
+	info := &MusicInfo{
+		Label:   firstValue(infoboxLookup(data, []string{"厂牌"}, false)),
+		Catalog: firstValue(infoboxLookup(data, []string{"碟片编号", "版本特性"}, false)),
+		Discs:   atoi(firstValue(infoboxLookup(data, []string{"碟片数量"}, false))),
+		Tracks:  tracks,
+	}
+	for _, v := range infoboxLookup(data, []string{"艺术家"}, false) {
+		info.Artists = append(info.Artists, splitNames(v)...)
+	}
+	for _, t := range tracks {
+		if t.Disc > info.Discs {
+			info.Discs = t.Disc
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,122 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBangumi_SubjectTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		genre []string
+		brand string
+		date  string
+	}{
+		{
+			name: "game",
+			data: `{"type": 4, "date": "2015-10-23", "infobox": [
+				{"key": "游戏类型", "value": "AVG"},
+				{"key": "开发", "value": "枕"},
+				{"key": "发行日期", "value": "2015年10月23日"}]}`,
+			genre: []string{"AVG"}, brand: "枕", date: "2015年10月23日",
+		},
+		{
+			name: "anime",
+			data: `{"type": 2, "platform": "TV", "date": "2022-10-05", "infobox": [
+				{"key": "动画制作", "value": "feel."},
+				{"key": "放送开始", "value": "2022年10月5日"}]}`,
+			genre: []string{"TV"}, brand: "feel.", date: "2022年10月5日",
+		},
+		{
+			name: "book",
+			data: `{"type": 1, "platform": "小说", "date": "2019-03-01", "infobox": [
+				{"key": "出版社", "value": [{"v": "KADOKAWA"}]}]}`,
+			genre: []string{"小说"}, brand: "KADOKAWA", date: "2019-03-01",
+		},
+		{
+			name: "music",
+			data: `{"type": 3, "infobox": [
+				{"key": "厂牌", "value": "Lantis"},
+				{"key": "发售日期", "value": "2015-11-25"}]}`,
+			brand: "Lantis", date: "2015-11-25",
+		},
+	}
+	b := &Bangumi{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			genre, _ := b.GetItemGenre(data)
+			brand, _ := b.GetItemBrand(data)
+			date, _ := b.GetItemReleaseDate(data)
+			if !reflect.DeepEqual(genre, tt.genre) || brand != tt.brand || date != tt.date {
+				t.Errorf("got genre %v brand %q date %q", genre, brand, date)
+			}
+			// 没有官网时的错误信息按条目类型区分
+			if _, err := b.GetItemLink(data); err == nil || strings.Contains(err.Error(), "游戏") != (tt.name == "game") {
+				t.Errorf("unexpected link error %v", err)
+			}
+		})
+	}
+}
+
+func TestBangumi_GetItemExtension(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0/episodes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("subject_id") {
+		case "1":
+			_, _ = w.Write([]byte(`{"total": 2, "data": [
+				{"id": 11, "type": 0, "sort": 1, "name": "第1話", "airdate": "2022-10-05", "duration": "00:24:00"},
+				{"id": 12, "type": 0, "sort": 2, "name": "第2話", "airdate": "2022-10-12"}]}`))
+		case "3":
+			_, _ = w.Write([]byte(`{"total": 2, "data": [
+				{"id": 31, "sort": 1, "disc": 1, "name": "OP"},
+				{"id": 32, "sort": 1, "disc": 2, "name": "ED"}]}`))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	b := &Bangumi{Domain: server.URL + "/"}
+
+	anime := &Item{}
+	err := b.GetItemExtension(anime, []byte(`{"id": 1, "type": 2, "platform": "TV", "total_episodes": 12,
+		"infobox": [{"key": "放送结束", "value": "2022年12月21日"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anime.Kind != "anime" || anime.Anime == nil || anime.Anime.Episodes != 12 || anime.Anime.EndDate != "2022年12月21日" ||
+		len(anime.Anime.List) != 2 || anime.Anime.List[1].Name != "第2話" {
+		t.Errorf("unexpected anime %+v %+v", anime, anime.Anime)
+	}
+
+	music := &Item{}
+	err = b.GetItemExtension(music, []byte(`{"id": 3, "type": 3, "infobox": [
+		{"key": "艺术家", "value": "fhána、佐藤純一"}, {"key": "厂牌", "value": "Lantis"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := music.Music; music.Kind != "music" || m == nil || m.Discs != 2 || len(m.Tracks) != 2 ||
+		!reflect.DeepEqual(m.Artists, []string{"fhána", "佐藤純一"}) || m.Label != "Lantis" {
+		t.Errorf("unexpected music %+v", music.Music)
+	}
+
+	book := &Item{}
+	err = b.GetItemExtension(book, []byte(`{"id": 2, "type": 1, "platform": "小说", "volumes": 0, "series": true, "infobox": [
+		{"key": "作者", "value": "すかぢ"}, {"key": "ISBN", "value": "978-4-04-000000-0"},
+		{"key": "册数", "value": "3册"}, {"key": "页数", "value": "320"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk := book.Book; book.Kind != "book" || bk == nil || bk.ISBN != "978-4-04-000000-0" || bk.Volumes != 3 ||
+		bk.Pages != 320 || !bk.Series || !reflect.DeepEqual(bk.Authors, []string{"すかぢ"}) {
+		t.Errorf("unexpected book %+v", book.Book)
+	}
+
+	game := &Item{}
+	if err = b.GetItemExtension(game, []byte(`{"id": 4, "type": 4}`)); err != nil || game.Kind != "" {
+		t.Errorf("unexpected game %+v err %v", game, err)
+	}
+}
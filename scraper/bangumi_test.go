@@ -33,3 +33,16 @@ func TestBangumi_GetItem(t *testing.T) {
 	}
 	fmt.Printf("%+v\n", item)
 }
+
+func TestBangumi_parseSearchResults(t *testing.T) {
+	results := BangumiScraper.parseSearchResults([]byte(`{"data": [
+		{"id": 1, "type": 4, "name": "サクラノ詩", "date": "2015-10-23"},
+		{"id": 2, "type": 4, "name_cn": "樱之刻", "infobox": [{"key": "开发", "value": "枕"}]}]}`))
+	if len(results) != 2 || results[0].Brand != "" || results[0].Uri != fmt.Sprintf(BangumiItemUri, "1") {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	// 只有带 infobox 的结果才有品牌
+	if results[1].Name != "樱之刻" || results[1].Brand != "枕" {
+		t.Errorf("unexpected result %+v", results[1])
+	}
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNoBrandSource 没有能处理该品牌页面的数据源
+var ErrNoBrandSource = errors.New("不支持的品牌页面")
+
+// brandMaxPages 品牌作品列表最多抓取的页数
+const brandMaxPages = 50
+
+// Brand 品牌（会社）
+type Brand struct {
+	Name      string            // 名称
+	Aliases   []string          // 别名
+	Link      string            // 官网
+	SourceIDs map[string]string // 各数据源的品牌编号，source -> ID
+	Works     []Release         // 作品列表
+}
+
+// BrandSource 可以抓取品牌页面的数据源
+type BrandSource interface {
+	GetBrand(uri string) (*Brand, error)
+}
+
+// AddAlias 记录别名，与名称或已有别名相同的会被忽略
+func (b *Brand) AddAlias(alias string) {
+	b.Aliases = appendUnique(b.Aliases, alias, b.Name)
+}
+
+// AddSourceID 记录品牌在数据源中的编号，已有的编号不会被覆盖
+func (b *Brand) AddSourceID(source, id string) {
+	addID(&b.SourceIDs, source, id)
+}
+
+// GetBrand 根据品牌页面地址选择对应的数据源抓取品牌及其作品
+//
+// 品牌页面地址可以从 Item.BrandUri 获得。
+func GetBrand(uri string) (*Brand, error) {
+	source, ok := brandSourceFor(uri)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoBrandSource, uri)
+	}
+	return source.GetBrand(uri)
+}
+
+func brandSourceFor(uri string) (BrandSource, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, false
+	}
+	host := u.Hostname()
+	switch {
+	case strings.HasSuffix(host, "getchu.com"):
+		return GetChuScraper, true
+	case strings.HasSuffix(host, "2dfan.org"), strings.HasSuffix(host, "2dfan.com"):
+		return TwoDFanScraper, true
+	case strings.HasSuffix(host, "bgm.tv"), strings.HasSuffix(host, "bangumi.tv"), strings.HasSuffix(host, "chii.in"):
+		return BangumiScraper, true
+	case strings.HasSuffix(host, "dmm.co.jp"):
+		return FanzaGamesScraper, true
+	}
+	return nil, false
+}
+
+// collectWorks 依次抓取分页，直到某一页没有新的作品
+func collectWorks(fetch func(page int) ([]Release, error)) ([]Release, error) {
+	var works []Release
+	seen := make(map[string]bool)
+	for page := 1; page <= brandMaxPages; page++ {
+		releases, err := fetch(page)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return works, err
+		}
+		added := 0
+		for _, r := range releases {
+			if r.Uri == "" || seen[r.Uri] {
+				continue
+			}
+			seen[r.Uri] = true
+			works = append(works, r)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return works, nil
+}
+
+func searchResultsToReleases(results []SearchResult) []Release {
+	releases := make([]Release, 0, len(results))
+	for _, r := range results {
+		releases = append(releases, Release{
+			Source:      r.Source,
+			Name:        r.Name,
+			Brand:       r.Brand,
+			ReleaseDate: r.ReleaseDate,
+			Uri:         r.Uri,
+		})
+	}
+	return releases
+}
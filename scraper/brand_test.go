@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"testing"
+)
+
+func TestGetBrand_Dispatch(t *testing.T) {
+	if _, err := GetBrand("https://example.com/brand/1"); !errors.Is(err, ErrNoBrandSource) {
+		t.Errorf("expected ErrNoBrandSource, got %v", err)
+	}
+	cases := map[string]BrandSource{
+		"https://www.getchu.com/php/search.phtml?search_brand_id=1234":   GetChuScraper,
+		"https://2dfan.org/subjects/tags/abc":                            TwoDFanScraper,
+		"https://bgm.tv/person/5678":                                     BangumiScraper,
+		"https://www.dmm.co.jp/mono/pcgame/-/list/=/article=maker/id=1/": FanzaGamesScraper,
+	}
+	for uri, want := range cases {
+		if got, ok := brandSourceFor(uri); !ok || got != want {
+			t.Errorf("brandSourceFor(%q) = %T, %v", uri, got, ok)
+		}
+	}
+}
+
+func TestCollectWorks(t *testing.T) {
+	pages := 0
+	works, err := collectWorks(func(page int) ([]Release, error) {
+		pages++
+		if page > 2 {
+			// 超出最后一页时网站返回最后一页的内容
+			page = 2
+		}
+		return []Release{{Uri: fmt.Sprintf("https://example.com/%d", page)}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works) != 2 || pages != 3 {
+		t.Errorf("expected 2 works from 3 pages, got %d works from %d pages", len(works), pages)
+	}
+}
+
+func TestGetChu_parseSearchResults(t *testing.T) {
+	html := `<html><body><ul class="display">
+		<li><a class="blueb" href="../soft.phtml?id=1232405">サクラノ刻</a>
+			<a href="search.phtml?search_brand_id=1234">枕</a> 発売日：2023/07/28</li>
+	</ul></body></html>`
+	encoded, _, err := transform.String(japanese.EUCJP.NewEncoder(), html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := GetChuScraper.parseSearchResults(root)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %+v", results)
+	}
+	r := results[0]
+	if r.Name != "サクラノ刻" || r.Brand != "枕" || r.ReleaseDate != "2023/07/28" ||
+		r.Uri != "https://www.getchu.com/soft.phtml?id=1232405" {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestBangumi_parseBrand(t *testing.T) {
+	persons := []byte(`[
+		{"id": 1, "name": "原画师", "relation": "原画"},
+		{"id": 2, "name": "发行商", "relation": "发行"},
+		{"id": 3, "name": "枕", "relation": "开发"}
+	]`)
+	if uri, _ := BangumiScraper.GetItemBrandUri(persons); uri != fmt.Sprintf(BangumiPersonUri, "3") {
+		t.Errorf("unexpected brand uri %q", uri)
+	}
+
+	person := []byte(`{"id": 3, "name": "枕", "infobox": [
+		{"key": "简体中文名", "value": "枕社"},
+		{"key": "别名", "value": [{"v": "makura"}, {"v": "枕"}]},
+		{"key": "website", "value": "http://www.makura-soft.com/"}
+	]}`)
+	subjects := []byte(`[
+		{"id": 226254, "type": 4, "name": "サクラノ刻", "staff": "开发"},
+		{"id": 226254, "type": 4, "name": "サクラノ刻", "staff": "发行"},
+		{"id": 100, "type": 2, "name": "アニメ", "staff": "原作"}
+	]`)
+	brand := parseBangumiBrand("3", person, subjects)
+	if brand.Name != "枕" || brand.Link != "http://www.makura-soft.com/" || brand.SourceIDs["bangumi"] != "3" {
+		t.Errorf("unexpected brand %+v", brand)
+	}
+	if len(brand.Aliases) != 2 || brand.Aliases[0] != "枕社" || brand.Aliases[1] != "makura" {
+		t.Errorf("unexpected aliases %v", brand.Aliases)
+	}
+	if len(brand.Works) != 1 || brand.Works[0].Uri != fmt.Sprintf(BangumiItemUri, "226254") {
+		t.Errorf("unexpected works %+v", brand.Works)
+	}
+}
+
+func TestFanzaGames_parseMakerWorks(t *testing.T) {
+	html := `<html><body><ul>
+		<li><a href="/mono/pcgame/-/detail/=/cid=1234abc/"><img alt="作品A" src="a.jpg"></a>
+			<a href="/mono/pcgame/-/detail/=/cid=1234abc/">作品A</a></li>
+		<li><a href="/mono/pcgame/-/detail/=/cid=5678def/"><img src="b.jpg"></a>
+			<a href="/mono/pcgame/-/detail/=/cid=5678def/">作品B</a></li>
+	</ul></body></html>`
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	works := FanzaGamesScraper.parseMakerWorks(root)
+	if len(works) != 2 || works[0].Name != "作品A" || works[1].Name != "作品B" ||
+		works[1].Uri != "https://www.dmm.co.jp/mono/pcgame/-/detail/=/cid=5678def/" {
+		t.Errorf("unexpected works %+v", works)
+	}
+}
@@ -0,0 +1,18 @@
+package scraper
+
+import "time"
+
+// Release 发售表中的一条
+type Release struct {
+	Source      string   // 来源
+	Name        string   // 名称
+	Brand       string   // 品牌
+	ReleaseDate string   // 发售日，2006-01-02
+	Genre       []string // 类别
+	Uri         string   // 详情页地址
+}
+
+// CalendarSource 提供月度发售表的数据源
+type CalendarSource interface {
+	GetReleaseCalendar(year int, month time.Month) ([]Release, error)
+}
@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"bytes"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"testing"
+	"time"
+)
+
+func TestGetChu_parseReleaseCalendar(t *testing.T) {
+	html := `<html><body><table>
+		<tr><td>発売日</td><td>タイトル</td><td>ブランド</td><td>ジャンル</td></tr>
+		<tr><td>07/28</td><td><a href="../soft.phtml?id=1232405">サクラノ刻</a></td><td>枕</td><td>ADV</td></tr>
+		<tr><td>07/28</td><td><a href="../soft.phtml?id=1232405">サクラノ刻</a></td><td>枕</td><td>ADV</td></tr>
+		<tr><td>07/31</td><td><a href="../soft.phtml?id=1232406">別の作品</a></td><td>ブランド</td><td></td></tr>
+	</table></body></html>`
+	encoded, _, err := transform.String(japanese.EUCJP.NewEncoder(), html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := GetChuScraper.parseReleaseCalendar(root, 2023)
+	if len(releases) != 2 {
+		t.Fatalf("expected 2 releases, got %+v", releases)
+	}
+	r := releases[0]
+	if r.Name != "サクラノ刻" || r.Brand != "枕" || r.ReleaseDate != "2023-07-28" ||
+		len(r.Genre) != 1 || r.Genre[0] != "ADV" || r.Uri != "https://www.getchu.com/soft.phtml?id=1232405" {
+		t.Errorf("unexpected release %+v", r)
+	}
+	if releases[1].Genre != nil {
+		t.Errorf("empty genre should be omitted: %+v", releases[1])
+	}
+}
+
+func TestFanzaGames_parseReleaseCalendar(t *testing.T) {
+	html := `<html><body><table>
+		<tr><th>7月28日(金)</th></tr>
+		<tr><td><a href="/mono/pcgame/-/detail/=/cid=1234abc/">作品A</a></td>
+			<td><a href="/mono/pcgame/-/list/=/article=maker/id=1/">メーカーA</a></td>
+			<td><a href="/mono/pcgame/-/list/=/article=keyword/id=2/">ADV</a><a href="/mono/pcgame/-/list/=/article=keyword/id=3/">学園</a></td></tr>
+		<tr><th>7/31</th></tr>
+		<tr><td><a href="/mono/pcgame/-/detail/=/cid=5678def/">作品B</a></td></tr>
+	</table></body></html>`
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := FanzaGamesScraper.parseReleaseCalendar(root, 2023, time.July)
+	if len(releases) != 2 {
+		t.Fatalf("expected 2 releases, got %+v", releases)
+	}
+	r := releases[0]
+	if r.Name != "作品A" || r.Brand != "メーカーA" || r.ReleaseDate != "2023-07-28" || len(r.Genre) != 2 ||
+		r.Uri != "https://www.dmm.co.jp/mono/pcgame/-/detail/=/cid=1234abc/" {
+		t.Errorf("unexpected release %+v", r)
+	}
+	if releases[1].ReleaseDate != "2023-07-31" {
+		t.Errorf("unexpected release %+v", releases[1])
+	}
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+)
+
+// 角色定位
+const (
+	CharacterMain       = "主角"
+	CharacterSupporting = "配角"
+	CharacterCameo      = "客串"
+)
+
+var (
+	heightRe       = regexp.MustCompile(`(?:身長|身高)\s*[:：]?\s*(\d{2,3}(?:\.\d)?)\s*(?:cm|㎝)?`)
+	measurementsRe = regexp.MustCompile(`(?i)(?:(?:スリーサイズ|三围|BWH)\s*[:：]?\s*(\d{2,3})\D{1,3}(\d{2,3})\D{1,3}(\d{2,3}))|(?:B\s*[:：]?\s*(\d{2,3})(?:\([A-Z]\))?\s*[/・、 ]?\s*W\s*[:：]?\s*(\d{2,3})\s*[/・、 ]?\s*H\s*[:：]?\s*(\d{2,3}))`)
+	birthdayRe     = regexp.MustCompile(`(?:(\d{4})\s*[年/-]\s*)?(\d{1,2})\s*[月/-]\s*(\d{1,2})\s*日?`)
+	birthdayKeyRe  = regexp.MustCompile(`(?:誕生日|生日)\s*[:：]\s*([^\s、，,]+)`)
+	bloodTypeRe    = regexp.MustCompile(`(?:血液型|血型)\s*[:：]\s*(AB|A|B|O)`)
+	ageRe          = regexp.MustCompile(`(?:年齢|年龄)\s*[:：]\s*(\d{1,3})`)
+)
+
+// NormalizeCharacterRole 将各数据源的角色定位统一为 主角、配角、客串
+func NormalizeCharacterRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "主角", "主人公", "メイン", "メインキャラクター", "main":
+		return CharacterMain
+	case "配角", "サブ", "サブキャラクター", "side", "supporting":
+		return CharacterSupporting
+	case "客串", "appears", "cameo":
+		return CharacterCameo
+	}
+	return strings.TrimSpace(role)
+}
+
+// AddAlias 记录别名，与名称或已有别名相同的会被忽略
+func (c *Character) AddAlias(alias string) {
+	c.Aliases = appendUnique(c.Aliases, alias, c.Name)
+}
+
+// AddSourceID 记录角色在数据源中的编号，已有的编号不会被覆盖
+func (c *Character) AddSourceID(source, id string) {
+	addID(&c.SourceIDs, source, id)
+}
+
+// SetTrait 记录其它属性，已有的属性不会被覆盖
+func (c *Character) SetTrait(key, value string) {
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if key == "" || value == "" {
+		return
+	}
+	if c.Traits == nil {
+		c.Traits = make(map[string]string)
+	}
+	if _, ok := c.Traits[key]; !ok {
+		c.Traits[key] = value
+	}
+}
+
+// parseBirthday 识别 “10月23日”、“1998年10月23日”、“10/23” 等生日写法
+func parseBirthday(s string) string {
+	m := birthdayRe.FindStringSubmatch(norm.NFKC.String(s))
+	if m == nil {
+		return ""
+	}
+	var month, day int
+	_, _ = fmt.Sscan(m[2], &month)
+	_, _ = fmt.Sscan(m[3], &day)
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return ""
+	}
+	if m[1] != "" {
+		return fmt.Sprintf("%s-%02d-%02d", m[1], month, day)
+	}
+	return fmt.Sprintf("%02d-%02d", month, day)
+}
+
+// parseCharacterProfile 从角色介绍文字中识别身高、三围、生日、血型和年龄，已有的值不会被覆盖
+func parseCharacterProfile(c *Character, text string) {
+	text = norm.NFKC.String(text)
+	if m := heightRe.FindStringSubmatch(text); m != nil && c.Height == "" {
+		c.Height = m[1] + "cm"
+	}
+	if m := measurementsRe.FindStringSubmatch(text); m != nil && c.Measurements == "" {
+		if m[1] != "" {
+			c.Measurements = fmt.Sprintf("B%s/W%s/H%s", m[1], m[2], m[3])
+		} else {
+			c.Measurements = fmt.Sprintf("B%s/W%s/H%s", m[4], m[5], m[6])
+		}
+	}
+	if m := birthdayKeyRe.FindStringSubmatch(text); m != nil && c.Birthday == "" {
+		c.Birthday = parseBirthday(m[1])
+	}
+	if m := bloodTypeRe.FindStringSubmatch(text); m != nil && c.BloodType == "" {
+		c.BloodType = m[1]
+	}
+	if m := ageRe.FindStringSubmatch(text); m != nil {
+		c.SetTrait("年龄", m[1])
+	}
+}
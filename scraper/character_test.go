@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"bytes"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tidwall/gjson"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"testing"
+)
+
+func TestParseCharacterProfile(t *testing.T) {
+	c := Character{}
+	parseCharacterProfile(&c, "身長：１５８cm　スリーサイズ：B82/W56/H84\n誕生日：10月23日　血液型：AB型　年齢：17")
+	if c.Height != "158cm" || c.Measurements != "B82/W56/H84" || c.Birthday != "10-23" ||
+		c.BloodType != "AB" || c.Traits["年龄"] != "17" {
+		t.Errorf("unexpected profile %+v", c)
+	}
+
+	c = Character{Height: "160cm"}
+	parseCharacterProfile(&c, "身高：155cm B：80(C) W：55 H：82 生日：1998年1月2日")
+	if c.Height != "160cm" || c.Measurements != "B80/W55/H82" || c.Birthday != "1998-01-02" {
+		t.Errorf("unexpected profile %+v", c)
+	}
+}
+
+func TestParseBangumiCharacter(t *testing.T) {
+	entry := gjson.Parse(`{"id": 1, "relation": "主角", "actors": [{"id": 9, "name": "遠野そよぎ"}]}`)
+	data := []byte(`{"id": 1, "name": "夏目藍", "gender": "female", "blood_type": 3,
+		"birth_mon": 10, "birth_day": 23, "summary": "s",
+		"infobox": [
+			{"key": "简体中文名", "value": "夏目蓝"},
+			{"key": "别名", "value": [{"k": "第二中文名", "v": "蓝"}, {"k": "纯假名", "v": "なつめ あい"}]},
+			{"key": "身高", "value": "158cm"},
+			{"key": "BWH", "value": "B82/W56/H84"},
+			{"key": "年龄", "value": "17"}
+		]}`)
+	c := parseBangumiCharacter(entry, data)
+	if c.Name != "夏目藍" || c.Role != CharacterMain || c.Gender != "女" || c.BloodType != "AB" ||
+		c.Birthday != "10-23" || c.Height != "158cm" || c.Measurements != "B82/W56/H84" ||
+		c.VoiceActor != "遠野そよぎ" || c.SourceIDs["bangumi"] != "1" || c.Traits["年龄"] != "17" {
+		t.Errorf("unexpected character %+v", c)
+	}
+	if len(c.Aliases) != 3 || c.Aliases[0] != "夏目蓝" {
+		t.Errorf("unexpected aliases %v", c.Aliases)
+	}
+}
+
+func TestGetChu_GetItemCharacter(t *testing.T) {
+	html := `<html><body><div class="tabletitle">キャラクター</div>
+		<table><tr>
+			<td><img src="/brandnew/1/c1.jpg"></td>
+			<td><h2 class="chara-name">夏目 藍（なつめ あい） CV：遠野そよぎ</h2>
+				<dl><dt>身長：158cm B:82 W:56 H:84</dt><dd>紹介</dd></dl></td>
+			<td><img src="/brandnew/1/c1b.jpg"></td>
+		</tr></table></body></html>`
+	encoded, _, err := transform.String(japanese.EUCJP.NewEncoder(), html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	characters, err := GetChuScraper.GetItemCharacter(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(characters) != 1 {
+		t.Fatalf("unexpected characters %+v", characters)
+	}
+	c := characters[0]
+	if c.Name != "夏目 藍" || c.VoiceActor != "遠野そよぎ" || len(c.Aliases) != 1 || c.Aliases[0] != "なつめ あい" ||
+		c.Height != "158cm" || c.Measurements != "B82/W56/H84" || c.Introduction != "紹介" {
+		t.Errorf("unexpected character %+v", c)
+	}
+}
+
+func TestMerge_Character(t *testing.T) {
+	a := &Item{Character: []Character{{Name: "c", SourceIDs: map[string]string{"getchu": "1"}}}}
+	b := &Item{Character: []Character{{Name: "c", Role: CharacterMain, Aliases: []string{"x"},
+		SourceIDs: map[string]string{"bangumi": "2"}}}}
+	m := Merge(a, b)
+	c := m.Character[0]
+	if c.Role != CharacterMain || len(c.Aliases) != 1 || len(c.SourceIDs) != 2 {
+		t.Errorf("unexpected merged character %+v", c)
+	}
+	if len(a.Character[0].SourceIDs) != 1 {
+		t.Errorf("merge must not modify its inputs")
+	}
+}
@@ -0,0 +1,265 @@
+package scraper
+
+import (
+	"fmt"
+	"reflect"
+	"scraper/tools"
+	"sort"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change 两个版本之间的一处变化
+type Change struct {
+	Field string      // 字段名，例如 ReleaseDate、Preview
+	Key   string      // 列表或字典中的元素，例如标签的 "分类/标签"、角色名
+	Kind  ChangeKind  // 新增、删除或修改
+	Old   interface{} // 旧值，新增时为空
+	New   interface{} // 新值，删除时为空
+}
+
+func (c Change) String() string {
+	field := c.Field
+	if c.Key != "" {
+		field += "[" + c.Key + "]"
+	}
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %v", field, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %v", field, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %v -> %v", field, c.Old, c.New)
+}
+
+type Changes []Change
+
+// Field 只保留指定字段的变化
+func (cs Changes) Field(name string) Changes {
+	var changes Changes
+	for _, c := range cs {
+		if c.Field == name {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Postponed 发售日是否推迟，只按两者中较粗的精度比较，2023-02 改为 2023-02-10 不算推迟
+func (cs Changes) Postponed() (Change, bool) {
+	for _, c := range cs.Field("ReleaseDate") {
+		if c.Kind != Changed {
+			continue
+		}
+		old, _ := c.Old.(string)
+		new, _ := c.New.(string)
+		old, new = tools.NormalizeDate(old), tools.NormalizeDate(new)
+		if n := len(old); len(new) < n {
+			old = old[:len(new)]
+		} else {
+			new = new[:n]
+		}
+		if old != "" && new > old {
+			return c, true
+		}
+	}
+	return Change{}, false
+}
+
+// Diff 比较两个版本的 Item
+//
+// 普通字段给出修改前后的值，列表字段给出逐个元素的增删，标签按 "分类/标识" 比较，角色按名称比较，
+// 制作人员按 "职务/姓名" 比较，关联条目按 "关系/来源:编号" 比较，infobox 按键比较。
+func Diff(old, new *Item) Changes {
+	if old == nil {
+		old = &Item{}
+	}
+	if new == nil {
+		new = &Item{}
+	}
+	var changes Changes
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		of, nf := ov.Field(i).Interface(), nv.Field(i).Interface()
+		switch o := of.(type) {
+		case []Tag:
+			changes = append(changes, diffKeyed(field.Name, tagKeys(o), tagKeys(nf.([]Tag)))...)
+		case []Character:
+			changes = append(changes, diffCharacters(o, nf.([]Character))...)
+		case []Staff:
+			changes = append(changes, diffKeyed(field.Name, staffKeys(o), staffKeys(nf.([]Staff)))...)
+		case []Relation:
+			changes = append(changes, diffKeyed(field.Name, relationKeys(o), relationKeys(nf.([]Relation)))...)
+		case Infobox:
+			changes = append(changes, diffKeyed(field.Name, infoboxKeys(o), infoboxKeys(nf.(Infobox)))...)
+		case []string:
+			changes = append(changes, diffStrings(field.Name, o, nf.([]string))...)
+		case map[string]string:
+			changes = append(changes, diffKeyed(field.Name, o, nf.(map[string]string))...)
+		case *tools.Torrent:
+			n := nf.(*tools.Torrent)
+			if hash(o) != hash(n) {
+				changes = append(changes, scalarChange(field.Name, hash(o), hash(n)))
+			}
+		default:
+			if !reflect.DeepEqual(of, nf) {
+				changes = append(changes, scalarChange(field.Name, of, nf))
+			}
+		}
+	}
+	return changes
+}
+
+func scalarChange(field string, old, new interface{}) Change {
+	kind := Changed
+	if reflect.ValueOf(old).IsZero() {
+		kind = Added
+	} else if reflect.ValueOf(new).IsZero() {
+		kind = Removed
+	}
+	return Change{Field: field, Kind: kind, Old: old, New: new}
+}
+
+func hash(t *tools.Torrent) string {
+	if t == nil {
+		return ""
+	}
+	return t.InfoHash
+}
+
+func diffStrings(field string, old, new []string) Changes {
+	var changes Changes
+	o, n := toSet(old), toSet(new)
+	for _, s := range old {
+		if !n[s] {
+			changes = append(changes, Change{Field: field, Key: s, Kind: Removed, Old: s})
+		}
+	}
+	for _, s := range new {
+		if !o[s] {
+			changes = append(changes, Change{Field: field, Key: s, Kind: Added, New: s})
+		}
+	}
+	return changes
+}
+
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
+
+func diffKeyed(field string, old, new map[string]string) Changes {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes Changes
+	for _, k := range keys {
+		o, inOld := old[k]
+		n, inNew := new[k]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Field: field, Key: k, Kind: Added, New: n})
+		case !inNew:
+			changes = append(changes, Change{Field: field, Key: k, Kind: Removed, Old: o})
+		case o != n:
+			changes = append(changes, Change{Field: field, Key: k, Kind: Changed, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// tagKeys 将标签展开为 "分类/标识" -> 名称
+func tagKeys(tags []Tag) map[string]string {
+	keys := make(map[string]string)
+	for _, tag := range tags {
+		category := tag.Category.Identity
+		if category == "" {
+			category = tag.Category.Name
+		}
+		for _, t := range tag.Item {
+			keys[category+"/"+t.Identity] = t.Name
+		}
+	}
+	return keys
+}
+
+// staffKeys 将制作人员展开为 "职务/姓名" -> 姓名
+func staffKeys(staff []Staff) map[string]string {
+	keys := make(map[string]string, len(staff))
+	for _, s := range staff {
+		keys[s.Role+"/"+s.Name] = s.Name
+	}
+	return keys
+}
+
+// infoboxKeys 将 infobox 展开为 键 -> 以 "、" 连接的值
+func infoboxKeys(ib Infobox) map[string]string {
+	keys := make(map[string]string, len(ib))
+	for _, entry := range ib {
+		keys[entry.Key] = strings.Join(entry.Strings(), "、")
+	}
+	return keys
+}
+
+// relationKeys 将关联条目展开为 "关系/来源:编号" -> 名称
+func relationKeys(relations []Relation) map[string]string {
+	keys := make(map[string]string, len(relations))
+	for _, r := range relations {
+		id := r.ID
+		if id == "" {
+			id = r.Uri
+		}
+		keys[string(r.Kind)+"/"+r.Source+":"+id] = r.Name
+	}
+	return keys
+}
+
+func diffCharacters(old, new []Character) Changes {
+	o := make(map[string]Character, len(old))
+	for _, c := range old {
+		o[c.Name] = c
+	}
+	n := make(map[string]Character, len(new))
+	for _, c := range new {
+		n[c.Name] = c
+	}
+
+	var changes Changes
+	for _, c := range old {
+		if _, ok := n[c.Name]; !ok {
+			changes = append(changes, Change{Field: "Character", Key: c.Name, Kind: Removed, Old: c})
+		}
+	}
+	for _, c := range new {
+		prev, ok := o[c.Name]
+		if !ok {
+			changes = append(changes, Change{Field: "Character", Key: c.Name, Kind: Added, New: c})
+		} else if !reflect.DeepEqual(prev, c) {
+			changes = append(changes, Change{Field: "Character", Key: c.Name, Kind: Changed, Old: prev, New: c})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package scraper
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	old := &Item{
+		Name:        "A",
+		ReleaseDate: "2023/06/30",
+		Preview:     []string{"p1", "p2"},
+		Tags:        []Tag{{Category: Category{Name: "类型"}, Item: []TagItem{{Identity: "adv", Name: "ADV"}}}},
+		Character:   []Character{{Name: "c1"}, {Name: "c2", Introduction: "old"}},
+	}
+	new := &Item{
+		Name:        "A",
+		Brand:       "brand",
+		ReleaseDate: "2023/07/28",
+		Preview:     []string{"p2", "p3"},
+		Tags:        []Tag{{Category: Category{Name: "类型"}, Item: []TagItem{{Identity: "adv", Name: "ADV"}, {Identity: "rpg", Name: "RPG"}}}},
+		Character:   []Character{{Name: "c2", Introduction: "new"}, {Name: "c3"}},
+		ExternalIDs: map[string]string{"bangumi": "1"},
+	}
+	changes := Diff(old, new)
+
+	want := map[string]ChangeKind{
+		"Brand":                Added,
+		"ReleaseDate":          Changed,
+		"Preview[p1]":          Removed,
+		"Preview[p3]":          Added,
+		"Tags[类型/rpg]":         Added,
+		"Character[c1]":        Removed,
+		"Character[c2]":        Changed,
+		"Character[c3]":        Added,
+		"ExternalIDs[bangumi]": Added,
+	}
+	got := make(map[string]ChangeKind)
+	for _, c := range changes {
+		key := c.Field
+		if c.Key != "" {
+			key += "[" + c.Key + "]"
+		}
+		got[key] = c.Kind
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	for k, kind := range want {
+		if got[k] != kind {
+			t.Errorf("change %s = %q, want %q", k, got[k], kind)
+		}
+	}
+
+	c, ok := changes.Postponed()
+	if !ok || c.New != "2023/07/28" {
+		t.Errorf("expected postponement, got %v %v", c, ok)
+	}
+	if _, ok = Diff(new, old).Postponed(); ok {
+		t.Error("earlier release date is not a postponement")
+	}
+	// 只补全了日期不算推迟，按较粗的精度比较
+	if _, ok = Diff(&Item{ReleaseDate: "2023-02"}, &Item{ReleaseDate: "2023-02-10"}).Postponed(); ok {
+		t.Error("a more precise release date is not a postponement")
+	}
+	if _, ok = Diff(&Item{ReleaseDate: "2023-02"}, &Item{ReleaseDate: "2023-03-01"}).Postponed(); !ok {
+		t.Error("expected postponement to the next month")
+	}
+	if len(Diff(old, old)) != 0 {
+		t.Error("identical items should have no changes")
+	}
+}
@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"scraper/tools"
+	"sync"
+)
+
+// AddExternalID 记录其它数据源的编号，已有的编号不会被覆盖
+func (item *Item) AddExternalID(source, id string) {
+	addID(&item.ExternalIDs, source, id)
+}
+
+// addID 在 ids 中记录数据源的编号，source 或 id 为空时忽略，已有的编号不会被覆盖
+func addID(ids *map[string]string, source, id string) {
+	if source == "" || id == "" {
+		return
+	}
+	if *ids == nil {
+		*ids = make(map[string]string)
+	}
+	if _, ok := (*ids)[source]; !ok {
+		(*ids)[source] = id
+	}
+}
+
+// AddExternalCodes 识别字符串中的商品编号和详情页链接并记录
+func (item *Item) AddExternalCodes(s string) {
+	for _, code := range tools.FindCodes(s) {
+		item.AddExternalID(code.Source, code.ID)
+	}
+}
+
+// IDIndex 外部编号索引，可由任意一个已知编号查到同一作品在其它数据源的编号
+type IDIndex struct {
+	lock   sync.RWMutex
+	groups map[string]map[string]string // "source:id" -> 同一作品的所有编号
+}
+
+func NewIDIndex() *IDIndex {
+	return &IDIndex{groups: make(map[string]map[string]string)}
+}
+
+func idKey(source, id string) string {
+	return source + ":" + id
+}
+
+// Add 将一组编号登记为同一作品，与已登记的编号有交集时会合并
+func (idx *IDIndex) Add(ids map[string]string) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	group := make(map[string]string)
+	var keys []string
+	for source, id := range ids {
+		if old, ok := idx.groups[idKey(source, id)]; ok {
+			for s, i := range old {
+				keys = append(keys, idKey(s, i))
+				if _, exist := group[s]; !exist {
+					group[s] = i
+				}
+			}
+		}
+	}
+	// 新传入的编号优先
+	for source, id := range ids {
+		if id != "" {
+			group[source] = id
+		}
+	}
+	for source, id := range group {
+		keys = append(keys, idKey(source, id))
+	}
+	// 被覆盖的旧编号仍然指向合并后的分组
+	for _, key := range keys {
+		idx.groups[key] = group
+	}
+}
+
+// AddItem 登记 Item 上的所有外部编号
+func (idx *IDIndex) AddItem(item *Item) {
+	if len(item.ExternalIDs) > 0 {
+		idx.Add(item.ExternalIDs)
+	}
+}
+
+// Resolve 返回与给定编号属于同一作品的所有编号，未登记时返回 nil
+func (idx *IDIndex) Resolve(source, id string) map[string]string {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	group, ok := idx.groups[idKey(source, id)]
+	if !ok {
+		return nil
+	}
+	ids := make(map[string]string, len(group))
+	for s, i := range group {
+		ids[s] = i
+	}
+	return ids
+}
+
+// ResolveURL 识别链接或编号字符串并解析
+func (idx *IDIndex) ResolveURL(s string) map[string]string {
+	code, ok := tools.ParseCode(s)
+	if !ok {
+		return nil
+	}
+	return idx.Resolve(code.Source, code.ID)
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import "testing"
+
+func TestIDIndex(t *testing.T) {
+	idx := NewIDIndex()
+	idx.AddItem(&Item{ExternalIDs: map[string]string{"bangumi": "226254", "getchu": "1219845"}})
+	idx.Add(map[string]string{"2dfan": "4566", "getchu": "1219845"})
+	idx.Add(map[string]string{"dlsite": "RJ123456"})
+
+	ids := idx.Resolve("2dfan", "4566")
+	if ids["bangumi"] != "226254" || ids["getchu"] != "1219845" || ids["2dfan"] != "4566" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+	if _, ok := ids["dlsite"]; ok {
+		t.Errorf("unrelated id resolved %v", ids)
+	}
+	if ids = idx.ResolveURL("https://api.bgm.tv/v0/subjects/226254"); ids["2dfan"] != "4566" {
+		t.Errorf("ResolveURL = %v", ids)
+	}
+	if ids = idx.Resolve("bangumi", "1"); ids != nil {
+		t.Errorf("unknown id resolved %v", ids)
+	}
+}
+
+func TestBangumi_GetItemExternalIDs(t *testing.T) {
+	data := []byte(`{"infobox":[
+		{"key":"website","value":"http://example.com/"},
+		{"key":"链接","value":[{"v":"https://www.getchu.com/soft.phtml?id=1219845"},{"v":"https://vndb.org/v2002"}]}
+	]}`)
+	ids, _ := BangumiScraper.GetItemExternalIDs(data)
+	if ids["getchu"] != "1219845" || ids["vndb"] != "v2002" || len(ids) != 2 {
+		t.Errorf("unexpected ids %v", ids)
+	}
+
+	item := &Item{}
+	item.AddExternalID("bangumi", "1")
+	item.AddExternalCodes("https://bgm.tv/subject/2 RJ123456")
+	if item.ExternalIDs["bangumi"] != "1" || item.ExternalIDs["dlsite"] != "RJ123456" {
+		t.Errorf("unexpected external ids %v", item.ExternalIDs)
+	}
+}
+
+func TestIDIndex_VersionNumbers(t *testing.T) {
+	// 自由文本中的版本号不能被当作编号，否则不同作品会被合并
+	a := &Item{}
+	a.AddExternalID("ggbases", "1")
+	a.AddExternalCodes("[Brand] Title v1.0 修正パッチ適用済み")
+	b := &Item{}
+	b.AddExternalID("ggbases", "2")
+	b.AddExternalCodes("Other_v1.0_Update")
+	ids, _ := BangumiScraper.GetItemExternalIDs([]byte(`{"infobox":[{"key":"版本","value":"v1.0"}]}`))
+	if len(a.ExternalIDs) != 1 || len(b.ExternalIDs) != 1 || len(ids) != 0 {
+		t.Fatalf("version numbers recognized as ids: %v %v %v", a.ExternalIDs, b.ExternalIDs, ids)
+	}
+
+	idx := NewIDIndex()
+	idx.AddItem(a)
+	idx.AddItem(b)
+	if got := idx.Resolve("ggbases", "1"); got["ggbases"] != "1" || len(got) != 1 {
+		t.Errorf("unrelated items linked: %v", got)
+	}
+}
@@ -1,17 +1,160 @@
 package scraper
 
+import (
+	"bytes"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"regexp"
+	"scraper/tools"
+	"strings"
+	"time"
+)
+
+var (
+	FanzaGamesDomain = "https://dlsoft.dmm.co.jp/"
+	// 月度发售表，参数为年、月
+	FanzaCalendarUri = "https://www.dmm.co.jp/mono/pcgame/-/calendar/=/year=%d/month=%02d/"
+	// メーカー作品列表，参数为メーカー编号、页码
+	FanzaMakerUri = "https://www.dmm.co.jp/mono/pcgame/-/list/=/article=maker/id=%s/page=%d/"
+
+	fanzaDayRe     = regexp.MustCompile(`(\d{1,2})/(\d{1,2})|(\d{1,2})日`)
+	fanzaMakerIDRe = regexp.MustCompile(`article=maker/id=(\d+)`)
+)
+
 type FanzaGames struct {
 	Proxy     string
 	Domain    string
 	SearchUri string
+	Headers   map[string]string
 }
 
 var FanzaGamesScraper *FanzaGames
 
+func (fg *FanzaGames) DoReq(url string) ([]byte, error) {
+	data, status, err := tools.MakeRequest("GET", url, fg.Proxy, nil, fg.Headers, nil)
+	if err != nil || status >= http.StatusBadRequest {
+		fmt.Println("do http error status =", status)
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetReleaseCalendar 获取指定月份的发售表
+func (fg *FanzaGames) GetReleaseCalendar(year int, month time.Month) ([]Release, error) {
+	data, err := fg.DoReq(fmt.Sprintf(FanzaCalendarUri, year, int(month)))
+	if err != nil {
+		return nil, err
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return fg.parseReleaseCalendar(root, year, month), nil
+}
+
+// parseReleaseCalendar 发售表按日期分组，日期行之后的各行都属于该日期
+func (fg *FanzaGames) parseReleaseCalendar(root *goquery.Document, year int, month time.Month) []Release {
+	var releases []Release
+	date := ""
+	root.Find("table tr").Each(func(i int, tr *goquery.Selection) {
+		if day := tr.Find("th, td.cal-day").First(); day.Length() > 0 {
+			if m := fanzaDayRe.FindStringSubmatch(day.Text()); m != nil {
+				if m[3] != "" {
+					date = tools.NormalizeDate(fmt.Sprintf("%d/%d/%s", year, int(month), m[3]))
+				} else {
+					date = tools.NormalizeDate(fmt.Sprintf("%d/%s/%s", year, m[1], m[2]))
+				}
+			}
+		}
+		a := tr.Find(`a[href*="/detail/=/cid="]`).First()
+		href, ok := a.Attr("href")
+		if !ok {
+			return
+		}
+		release := Release{
+			Source:      "fanza",
+			Name:        strings.TrimSpace(a.Text()),
+			Brand:       strings.TrimSpace(tr.Find(`a[href*="article=maker"]`).First().Text()),
+			ReleaseDate: date,
+			Uri:         tools.AbsImage("https://www.dmm.co.jp/", href),
+		}
+		tr.Find(`a[href*="article=keyword"]`).Each(func(i int, g *goquery.Selection) {
+			release.Genre = append(release.Genre, strings.TrimSpace(g.Text()))
+		})
+		releases = append(releases, release)
+	})
+	return releases
+}
+
+// GetBrand 抓取メーカー的作品列表，uri 为包含 article=maker/id= 的列表页面
+func (fg *FanzaGames) GetBrand(uri string) (*Brand, error) {
+	m := fanzaMakerIDRe.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoBrandSource, uri)
+	}
+	brand := &Brand{}
+	brand.AddSourceID("fanza", m[1])
+	works, err := collectWorks(func(page int) ([]Release, error) {
+		data, err := fg.DoReq(fmt.Sprintf(FanzaMakerUri, m[1], page))
+		if err != nil {
+			return nil, err
+		}
+		root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+		if err != nil {
+			return nil, err
+		}
+		if brand.Name == "" {
+			brand.Name = strings.TrimSpace(root.Find("h1").First().Text())
+		}
+		return fg.parseMakerWorks(root), nil
+	})
+	brand.Works = works
+	for i := range brand.Works {
+		brand.Works[i].Brand = brand.Name
+	}
+	return brand, err
+}
+
+// parseMakerWorks 列表中的作品链接可能是封面图，名称取图片的 alt
+func (fg *FanzaGames) parseMakerWorks(root *goquery.Document) []Release {
+	var releases []Release
+	seen := make(map[string]bool)
+	root.Find(`a[href*="/detail/=/cid="]`).Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		uri := tools.AbsImage("https://www.dmm.co.jp/", href)
+		name := strings.TrimSpace(a.Text())
+		if name == "" {
+			name, _ = a.Find("img").First().Attr("alt")
+			name = strings.TrimSpace(name)
+		}
+		if seen[uri] {
+			// 同一作品的封面和标题各有一个链接，以有名称的为准
+			if name != "" {
+				for j := range releases {
+					if releases[j].Uri == uri && releases[j].Name == "" {
+						releases[j].Name = name
+					}
+				}
+			}
+			return
+		}
+		seen[uri] = true
+		releases = append(releases, Release{Source: "fanza", Name: name, Uri: uri})
+	})
+	return releases
+}
+
 func init() {
+	headers := make(map[string]string)
+	headers["User-Agent"] = defaultUserAgent
+	headers["Accept-Language"] = "ja-JP,ja;q=0.9"
+	// 跳过年龄确认页面
+	headers["Cookie"] = "age_check_done=1"
 	FanzaGamesScraper = &FanzaGames{
-		Proxy:     "socks5://127.0.0.1:7890",
-		Domain:    "https://dlsoft.dmm.co.jp/",
+		Proxy:     defaultProxy,
+		Domain:    FanzaGamesDomain,
 		SearchUri: "https://dlsoft.dmm.co.jp/search?service=pcgame&floor=digital_pcgame&searchstr=%d",
+		Headers:   headers,
 	}
 }
@@ -7,15 +7,31 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
 	"scraper/tools"
 	"strings"
 	"time"
 )
 
 var (
-	GetChuDomain = "https://www.getchu.com/"
+	GetChuDomain    = "https://www.getchu.com/"
+	GetChuSearchUri = "https://www.getchu.com/php/search.phtml?genre=pc_soft&check_key_dtl=1&submit=&search_keyword=%s"
+	// 月度发售表，参数为年、月
+	GetChuCalendarUri = "https://www.getchu.com/all/month_title.html?genre=pc_soft&gage=all&year=%d&month=%d"
+	// 品牌作品列表，参数为品牌编号、页码
+	GetChuBrandUri = "https://www.getchu.com/php/search.phtml?genre=pc_soft&search_brand_id=%s&pageID=%d"
+
+	janRe           = regexp.MustCompile(`\d{13}|\d{8}`)
+	monthDayRe      = regexp.MustCompile(`(\d{1,2})/(\d{1,2})`)
+	getChuDateRe    = regexp.MustCompile(`\d{4}/\d{2}/\d{2}`)
+	getChuBrandIDRe = regexp.MustCompile(`brand_id=(\d+)`)
+	getChuReadingRe = regexp.MustCompile(`^(.+?)\s*[（(]([^）)]+)[）)]$`)
+	getChuCVRe      = regexp.MustCompile(`\s*[（(]?\s*(?:CV|ＣＶ)\s*[：:]\s*([^）)]+)[）)]?\s*$`)
 )
 
 type GetChu struct {
@@ -71,6 +87,7 @@ func (gc *GetChu) GetItem(uri string) (*Item, error) {
 		return nil, err
 	}
 	item := &Item{Origin: uri}
+	item.AddExternalCodes(uri)
 	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
@@ -81,6 +98,8 @@ func (gc *GetChu) GetItem(uri string) (*Item, error) {
 	if err != nil {
 		fmt.Println("获取名称失败 url:", uri, "err:", err)
 	}
+	item.OriginalTitle = item.Name
+	item.AddTitle(LangJa, item.Name)
 	// 获取预览图
 	item.Preview, err = gc.GetItemPreview(root)
 	if err != nil {
@@ -106,6 +125,11 @@ func (gc *GetChu) GetItem(uri string) (*Item, error) {
 	if err != nil {
 		fmt.Println("获取官网链接失败 url:", uri, "err:", err)
 	}
+	// 获取品牌页面
+	item.BrandUri, err = gc.GetItemBrandUri(root)
+	if err != nil {
+		fmt.Println("获取品牌页面失败 url:", uri, "err:", err)
+	}
 	// 获取故事简介链接
 	item.Story, err = gc.GetItemStory(root)
 	if err != nil {
@@ -116,6 +140,17 @@ func (gc *GetChu) GetItem(uri string) (*Item, error) {
 	if err != nil {
 		fmt.Println("获取角色信息失败 url:", uri, "err:", err)
 	}
+	// 获取制作人员
+	item.Staff, err = gc.GetItemStaff(root)
+	if err != nil {
+		fmt.Println("获取制作人员失败 url:", uri, "err:", err)
+	}
+	// 获取 JAN 码
+	jan, err := gc.GetItemJAN(root)
+	if err != nil {
+		fmt.Println("获取JAN码失败 url:", uri, "err:", err)
+	}
+	item.AddExternalID("jan", jan)
 
 	return item, nil
 }
@@ -175,6 +210,75 @@ func (gc *GetChu) GetItemLink(node *goquery.Document) (string, error) {
 	return link, nil
 }
 
+// GetItemBrandUri 获取品牌栏中“このブランドの作品一覧”的链接
+func (gc *GetChu) GetItemBrandUri(node *goquery.Document) (string, error) {
+	link, ok := node.Find(`#soft_table tr:nth-child(2) table tr:nth-child(1) td:nth-child(2) a[href*="brand_id="]`).
+		First().Attr("href")
+	if !ok {
+		return "", nil
+	}
+	return tools.AbsImage(gc.Domain+"php/", link), nil
+}
+
+// GetItemJAN 获取商品信息表中的 JAN 码
+func (gc *GetChu) GetItemJAN(node *goquery.Document) (string, error) {
+	var jan string
+	node.Find("#soft_table tr").Each(func(i int, tr *goquery.Selection) {
+		if jan != "" {
+			return
+		}
+		if strings.Contains(tools.Jp2Utf8([]byte(tr.Find("td").First().Text())), "JAN") {
+			jan = janRe.FindString(tr.Find("td").Eq(1).Text())
+		}
+	})
+	return jan, nil
+}
+
+// GetItemStaff 获取商品信息表中 原画、シナリオ 等职务的人员
+func (gc *GetChu) GetItemStaff(node *goquery.Document) ([]Staff, error) {
+	item := &Item{}
+	node.Find("#soft_table tr").Each(func(i int, tr *goquery.Selection) {
+		tds := tr.ChildrenFiltered("td")
+		if tds.Length() < 2 {
+			return
+		}
+		role := tools.Jp2Utf8([]byte(tds.First().Text()))
+		if !isStaffRole(role) {
+			return
+		}
+		value := tds.Eq(1)
+		var names []string
+		if links := value.Find("a"); links.Length() > 0 {
+			links.Each(func(i int, a *goquery.Selection) {
+				names = append(names, tools.Jp2Utf8([]byte(a.Text())))
+			})
+		} else {
+			names = splitNames(tools.Jp2Utf8([]byte(value.Text())))
+		}
+		for _, name := range names {
+			item.AddStaff(Staff{Role: role, Name: name})
+		}
+	})
+	return item.Staff, nil
+}
+
+// splitCharaName 角色名后面可能带有 “CV：声优”
+func splitCharaName(s string) (name, voiceActor string) {
+	s = strings.TrimSpace(s)
+	if loc := getChuCVRe.FindStringSubmatchIndex(s); loc != nil {
+		return strings.TrimSpace(s[:loc[0]]), strings.TrimSpace(s[loc[2]:loc[3]])
+	}
+	return s, ""
+}
+
+// splitReading 拆分 “夏目 藍（なつめ あい）” 形式的名字和读音
+func splitReading(s string) (name, reading string) {
+	if m := getChuReadingRe.FindStringSubmatch(s); m != nil {
+		return strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+	}
+	return s, ""
+}
+
 func (gc *GetChu) GetItemStory(node *goquery.Document) (string, error) {
 	var story string
 	node.Find("div.tabletitle").Each(func(i int, selection *goquery.Selection) {
@@ -198,15 +302,21 @@ func (gc *GetChu) GetItemCharacter(node *goquery.Document) ([]Character, error)
 					return
 				}
 				avatar, _ := selection.Find("td:nth-child(1) img").Attr("src")
-				name := tools.Jp2Utf8([]byte(selection.Find("td:nth-child(2) h2.chara-name").Text()))
+				name, voiceActor := splitCharaName(tools.Jp2Utf8([]byte(selection.Find("td:nth-child(2) h2.chara-name").Text())))
+				name, reading := splitReading(name)
 				introduction := tools.Jp2Utf8([]byte(selection.Find("td:nth-child(2) dd").Text()))
 				image, _ := selection.Find("td:nth-child(3) img").Attr("src")
-				character = append(character, Character{
+				c := Character{
 					Name:         name,
 					Introduction: introduction,
 					Avatar:       tools.AbsImage(gc.Domain, avatar),
 					Images:       []string{tools.AbsImage(gc.Domain, image)},
-				})
+					VoiceActor:   voiceActor,
+				}
+				c.AddAlias(reading)
+				// 身长、三围等写在名字下方或介绍中
+				parseCharacterProfile(&c, tools.Jp2Utf8([]byte(selection.Find("td:nth-child(2)").Text())))
+				character = append(character, c)
 			})
 			return
 		}
@@ -215,6 +325,118 @@ func (gc *GetChu) GetItemCharacter(node *goquery.Document) ([]Character, error)
 	return character, nil
 }
 
+func (gc *GetChu) Search(keyword string) ([]SearchResult, error) {
+	// Getchu 使用 EUC-JP 编码的关键词
+	encoded, _, err := transform.String(japanese.EUCJP.NewEncoder(), keyword)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gc.DoReq(fmt.Sprintf(gc.SearchUri, url.QueryEscape(encoded)))
+	if err != nil {
+		return nil, err
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return gc.parseSearchResults(root), nil
+}
+
+// parseSearchResults 解析搜索结果页，品牌作品列表也使用同样的页面
+func (gc *GetChu) parseSearchResults(root *goquery.Document) []SearchResult {
+	var results []SearchResult
+	root.Find("ul.display li").Each(func(i int, li *goquery.Selection) {
+		a := li.Find(`a.blueb[href*="soft.phtml"]`).First()
+		href, ok := a.Attr("href")
+		if !ok {
+			return
+		}
+		text := tools.Jp2Utf8([]byte(li.Text()))
+		results = append(results, SearchResult{
+			Source:      "getchu",
+			Name:        strings.TrimSpace(tools.Jp2Utf8([]byte(a.Text()))),
+			Brand:       strings.TrimSpace(tools.Jp2Utf8([]byte(li.Find(`a[href*="search_brand_id"]`).First().Text()))),
+			ReleaseDate: getChuDateRe.FindString(text),
+			Uri:         tools.AbsImage(gc.Domain+"php/", href),
+		})
+	})
+	return results
+}
+
+// GetBrand 抓取品牌的作品列表，uri 为包含 brand_id 的品牌页面
+func (gc *GetChu) GetBrand(uri string) (*Brand, error) {
+	m := getChuBrandIDRe.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoBrandSource, uri)
+	}
+	brand := &Brand{}
+	brand.AddSourceID("getchu", m[1])
+	works, err := collectWorks(func(page int) ([]Release, error) {
+		data, err := gc.DoReq(fmt.Sprintf(GetChuBrandUri, m[1], page))
+		if err != nil {
+			return nil, err
+		}
+		root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+		if err != nil {
+			return nil, err
+		}
+		return searchResultsToReleases(gc.parseSearchResults(root)), nil
+	})
+	brand.Works = works
+	for _, w := range works {
+		if w.Brand != "" {
+			brand.Name = w.Brand
+			break
+		}
+	}
+	return brand, err
+}
+
+// GetReleaseCalendar 获取指定月份的发售表
+func (gc *GetChu) GetReleaseCalendar(year int, month time.Month) ([]Release, error) {
+	data, err := gc.DoReq(fmt.Sprintf(GetChuCalendarUri, year, int(month)))
+	if err != nil {
+		return nil, err
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	return gc.parseReleaseCalendar(root, year), nil
+}
+
+// parseReleaseCalendar 发售表每行依次为 发售日、标题、品牌、类别
+func (gc *GetChu) parseReleaseCalendar(root *goquery.Document, year int) []Release {
+	var releases []Release
+	seen := make(map[string]bool)
+	root.Find(`a[href*="soft.phtml?id="]`).Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		uri := tools.AbsImage(gc.Domain+"all/", href)
+		name := strings.TrimSpace(tools.Jp2Utf8([]byte(a.Text())))
+		if name == "" || seen[uri] {
+			return
+		}
+		seen[uri] = true
+
+		release := Release{Source: "getchu", Name: name, Uri: uri}
+		td := a.Closest("td")
+		cells := td.Parent().Children()
+		for j := 0; j < td.Index(); j++ {
+			if m := monthDayRe.FindStringSubmatch(cells.Eq(j).Text()); m != nil {
+				release.ReleaseDate = tools.NormalizeDate(fmt.Sprintf("%d/%s/%s", year, m[1], m[2]))
+			}
+		}
+		if brand := td.Next(); brand.Length() > 0 {
+			release.Brand = strings.TrimSpace(tools.Jp2Utf8([]byte(brand.Text())))
+			if genre := strings.TrimSpace(tools.Jp2Utf8([]byte(brand.Next().Text()))); genre != "" {
+				release.Genre = []string{genre}
+			}
+		}
+		releases = append(releases, release)
+	})
+	return releases
+}
+
 func init() {
 	headers := make(map[string]string)
 	headers["User-Agent"] = defaultUserAgent
@@ -224,7 +446,8 @@ func init() {
 	GetChuScraper = &GetChu{
 		Proxy:     defaultProxy,
 		Domain:    GetChuDomain,
-		SearchUri: "",
+		SearchUri: GetChuSearchUri,
 		Headers:   headers,
 	}
+	Register("getchu", GetChuScraper)
 }
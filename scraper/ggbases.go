@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"scraper/tools"
 	"strconv"
@@ -27,11 +28,21 @@ var GGBasesSearchUri = "https://ggbases.dlgal.com/search.so?p=0&title=%d&advance
 var GGBasesMagnetUri = "https://ggbases.dlgal.com/magnet.so?id=%s"
 var GGBasesBtUri = "https://ggbases.dlgal.com/down.so?id=%s"
 
+// GGBasesMagnetTimeout 等待磁链接口响应的最长时间
+var GGBasesMagnetTimeout = 15 * time.Second
+
+// GGBasesMagnetApiRe 点击磁链按钮后请求的接口路径，只匹配路径，不匹配查询参数
+var GGBasesMagnetApiRe = regexp.MustCompile(`(?i)magnet`)
+
+// ErrMagnetUnavailable 资源没有可用的磁力链接
+var ErrMagnetUnavailable = errors.New("磁链不可用")
+
 type GGBases struct {
-	Proxy     string
-	Domain    string
-	SearchUri string
-	Headers   map[string]string
+	Proxy       string
+	Domain      string
+	SearchUri   string
+	Headers     map[string]string
+	DownloadDir string // 种子保存目录，为空时使用当前工作目录
 }
 
 var GGBasesScraper *GGBases
@@ -80,6 +91,7 @@ func (gg *GGBases) GetItem(uri string) (*Item, error) {
 		return nil, err
 	}
 	item := &Item{Origin: uri, proxy: gg.Proxy}
+	item.AddExternalCodes(uri)
 	root, err := goquery.NewDocumentFromReader(bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
@@ -136,6 +148,10 @@ func (gg *GGBases) GetItem(uri string) (*Item, error) {
 	if err != nil {
 		fmt.Println("获取大小失败 url:", uri, "err:", err)
 	}
+	item.SizeBytes, err = tools.ParseSize(item.Size, false)
+	if err != nil {
+		fmt.Println("解析大小失败 url:", uri, "err:", err)
+	}
 	// 获取磁链
 	u, err := url.Parse(uri)
 	if err == nil {
@@ -162,11 +178,25 @@ func (gg *GGBases) GetItem(uri string) (*Item, error) {
 	} else {
 		fmt.Println("获取bt文件失败 url:", uri, "err:", err)
 	}
+	// 解析 bt 文件
+	if item.BtFile != "" {
+		item.Torrent, err = tools.ParseTorrentFile(item.BtFile)
+		if err == nil {
+			item.VerifySize(item.Torrent.Length)
+			if item.Magnet == "" {
+				item.Magnet = item.Torrent.Magnet()
+			}
+		} else {
+			fmt.Println("解析bt文件失败 url:", uri, "err:", err)
+		}
+	}
 	// 获取其他信息
 	item.OtherInfo, err = gg.GetItemOtherInfo(root)
 	if err != nil {
 		fmt.Println("获取其他信息失败 url:", uri, "err:", err)
 	}
+	// 介绍中一般附有官网、DLsite、Getchu 等链接
+	item.AddExternalCodes(item.OtherInfo)
 	return item, nil
 }
 
@@ -278,75 +308,195 @@ func (gg GGBases) GetItemSize(node *goquery.Document) (string, error) {
 	return node.Find("#touch tbody tr:nth-child(5) td:nth-child(2) span").Text(), nil
 }
 func (gg GGBases) GetItemMagnet(id string) (string, error) {
-	data := make(chan []byte, 1)
+	var hash string
+	var err error
 	f := func(ctx context.Context) {
-		chromedp.ListenTarget(ctx, func(ev interface{}) {
-			switch ev := ev.(type) {
+		ctx, cancel := context.WithTimeout(ctx, GGBasesMagnetTimeout)
+		defer cancel()
 
-			case *network.EventResponseReceived:
-				if ev.Type != "XHR" {
-					return
-				}
-				resp := ev.Response
-				if resp.Status > http.StatusBadRequest {
-					fmt.Println(resp.Status)
-					return
-				}
-				fmt.Println(resp.Status)
-				go func() {
-					c := chromedp.FromContext(ctx)
-					buf, err := network.GetResponseBody(ev.RequestID).Do(cdp.WithExecutor(ctx, c.Target))
-					if err != nil {
-						fmt.Println("get xhr resp body err:", err)
-					}
-					data <- buf
-				}()
+		// 页面上没有磁链按钮时直接返回，避免 Click 一直等待
+		var nodes []*cdp.Node
+		err = chromedp.Run(ctx, chromedp.Nodes(".dbutton[bt='3']", &nodes, chromedp.AtLeast(0)))
+		if err != nil {
+			return
+		}
+		if len(nodes) == 0 {
+			err = fmt.Errorf("%w: id=%s", ErrMagnetUnavailable, id)
+			return
+		}
+
+		listener := newMagnetListener(gg.Domain, id)
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			e, ok := ev.(*network.EventResponseReceived)
+			if !ok || e.Type != network.ResourceTypeXHR {
+				return
 			}
+			listener.response(e.Response.URL, e.Response.Status, func() ([]byte, error) {
+				c := chromedp.FromContext(ctx)
+				return network.GetResponseBody(e.RequestID).Do(cdp.WithExecutor(ctx, c.Target))
+			})
 		})
-		chromedp.Run(ctx,
-			network.Enable(),
-			chromedp.Click(".dbutton[bt='3']"),
-			chromedp.Sleep(time.Second*1),
-		)
+		err = chromedp.Run(ctx, network.Enable(), chromedp.Click(".dbutton[bt='3']"))
+		if err != nil {
+			return
+		}
+		select {
+		case r := <-listener.result:
+			hash, err = r.hash, r.err
+		case <-ctx.Done():
+			err = fmt.Errorf("%w: id=%s 等待磁链响应超时", ErrMagnetUnavailable, id)
+		}
+	}
+	_, reqErr := gg.DoChromeReq(fmt.Sprintf(GGBasesMagnetUri, id), false, f)
+	if reqErr != nil {
+		return "", reqErr
 	}
-	_, err := gg.DoChromeReq(fmt.Sprintf(GGBasesMagnetUri, id), false, f)
 	if err != nil {
 		return "", err
 	}
-	body := <-data
-	fmt.Println("magnet =", string(body))
-	hash := gjson.GetBytes(body, "hash").String()
 	return fmt.Sprintf("magnet:?xt=urn:btih:%s", hash), nil
 }
 
+type magnetResult struct {
+	hash string
+	err  error
+}
+
+// magnetListener 从页面的 XHR 响应中找出磁链接口的结果
+//
+// 只接受 ggbases 域名下、路径匹配 GGBasesMagnetApiRe 且 id 参数为当前资源的响应，
+// 页面上统计、广告等请求即使在查询参数中带有 magnet 也会被忽略。
+type magnetListener struct {
+	host   string
+	id     string
+	result chan magnetResult
+}
+
+func newMagnetListener(domain, id string) *magnetListener {
+	l := &magnetListener{id: id, result: make(chan magnetResult, 1)}
+	if u, err := url.Parse(domain); err == nil {
+		l.host = u.Host
+	}
+	return l
+}
+
+// match 是否为当前资源的磁链接口
+func (l *magnetListener) match(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host != l.host || !GGBasesMagnetApiRe.MatchString(u.Path) {
+		return false
+	}
+	return u.Query().Get("id") == l.id
+}
+
+// response 处理一个 XHR 响应，只保留第一个磁链接口的结果，body 在另一个 goroutine 中读取
+func (l *magnetListener) response(uri string, status int64, body func() ([]byte, error)) {
+	if !l.match(uri) {
+		return
+	}
+	if status >= http.StatusBadRequest {
+		l.send(magnetResult{err: fmt.Errorf("%w: id=%s status=%d", ErrMagnetUnavailable, l.id, status)})
+		return
+	}
+	go func() {
+		data, err := body()
+		if err != nil {
+			l.send(magnetResult{err: fmt.Errorf("读取磁链响应失败 id=%s: %w", l.id, err)})
+			return
+		}
+		h, err := parseMagnetHash(l.id, data)
+		l.send(magnetResult{hash: h, err: err})
+	}()
+}
+
+func (l *magnetListener) send(r magnetResult) {
+	select {
+	case l.result <- r:
+	default:
+	}
+}
+
+// parseMagnetHash 从磁链接口的响应中取出 hash，没有时视为磁链不可用
+func parseMagnetHash(id string, body []byte) (string, error) {
+	if h := gjson.GetBytes(body, "hash").String(); h != "" {
+		return h, nil
+	}
+	return "", fmt.Errorf("%w: id=%s 响应中没有 hash", ErrMagnetUnavailable, id)
+}
+
 func (gg GGBases) GetItemBtFile(id string) (string, error) {
-	done := make(chan string, 1)
-	wd, err := os.Getwd()
+	dir := gg.DownloadDir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
 	}
+
+	var filename string
 	f := func(ctx context.Context) {
+		names := make(map[string]string)
+		done := make(chan string, 1)
+		var lock sync.Mutex
 		chromedp.ListenTarget(ctx, func(v interface{}) {
-			if ev, ok := v.(*browser.EventDownloadProgress); ok {
+			switch ev := v.(type) {
+			case *browser.EventDownloadWillBegin:
+				lock.Lock()
+				names[ev.GUID] = ev.SuggestedFilename
+				lock.Unlock()
+			case *browser.EventDownloadProgress:
 				if ev.State == browser.DownloadProgressStateCompleted {
-					done <- ev.GUID
-					close(done)
+					select {
+					case done <- ev.GUID:
+					default:
+					}
 				}
 			}
 		})
-		chromedp.Run(ctx,
+		err = chromedp.Run(ctx,
 			network.Enable(),
 			browser.
-				SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllow).
-				WithDownloadPath(wd).
+				SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+				WithDownloadPath(dir).
 				WithEventsEnabled(true),
 			chromedp.Click(".dbutton[bt='1']"),
-			chromedp.Sleep(time.Second),
 		)
+		if err != nil {
+			return
+		}
+		select {
+		case guid := <-done:
+			// AllowAndName 模式下文件以 GUID 命名，下载完成后改回原文件名
+			lock.Lock()
+			name := names[guid]
+			lock.Unlock()
+			if name == "" {
+				name = id + ".torrent"
+			}
+			filename = filepath.Join(dir, filepath.Base(name))
+			err = os.Rename(filepath.Join(dir, guid), filename)
+		case <-ctx.Done():
+			err = fmt.Errorf("等待种子下载超时: %w", ctx.Err())
+		}
+	}
+	_, reqErr := gg.DoChromeReq(fmt.Sprintf(GGBasesBtUri, id), false, f)
+	if reqErr != nil {
+		return "", reqErr
 	}
-	_, err = gg.DoChromeReq(fmt.Sprintf(GGBasesBtUri, id), false, f)
-	return "", err
+	if err != nil {
+		return "", err
+	}
+	return filename, nil
 }
+
 func (gg GGBases) GetItemOtherInfo(node *goquery.Document) (string, error) {
 	return node.Find("#description div[markdown-text]").Html()
 }
@@ -366,4 +516,5 @@ func init() {
 		SearchUri: GGBasesSearchUri,
 		Headers:   headers,
 	}
+	Register("ggbases", GGBasesScraper)
 }
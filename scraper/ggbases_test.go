@@ -1,9 +1,11 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGGBases_DO(t *testing.T) {
@@ -32,3 +34,40 @@ func TestGGBases_GetItem(t *testing.T) {
 	}
 	fmt.Printf("%+v\n", item)
 }
+
+func TestGGBases_MagnetResponse(t *testing.T) {
+	l := newMagnetListener(GGBasesDomain, "1")
+	for uri, want := range map[string]bool{
+		"https://ggbases.dlgal.com/magnet/get?id=1":                                                        true,
+		"https://ggbases.dlgal.com/magnet/get?id=2":                                                        false,
+		"https://www.google-analytics.com/collect?dl=https%3A%2F%2Fggbases.dlgal.com%2Fmagnet.so%3Fid%3D1": false,
+		"https://ggbases.dlgal.com/stat.so?page=/magnet.so&id=1":                                           false,
+		"https://cdn.example.com/magnet/get?id=1":                                                          false,
+	} {
+		if got := l.match(uri); got != want {
+			t.Errorf("match(%s) = %v, want %v", uri, got, want)
+		}
+	}
+	// 带有 magnet 的无关 XHR 先到达时不影响结果
+	l.response("https://www.google-analytics.com/collect?dl=magnet.so%3Fid%3D1", 200, func() ([]byte, error) {
+		return []byte(`{}`), nil
+	})
+	l.response("https://ggbases.dlgal.com/stat.so?page=/magnet.so&id=1", 404, nil)
+	l.response("https://ggbases.dlgal.com/magnet/get?id=1", 200, func() ([]byte, error) {
+		return []byte(`{"hash": "abc"}`), nil
+	})
+	select {
+	case r := <-l.result:
+		if r.err != nil || r.hash != "abc" {
+			t.Errorf("unexpected magnet result %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Error("magnet response was not received")
+	}
+	if h, err := parseMagnetHash("1", []byte(`{"hash": "abc"}`)); err != nil || h != "abc" {
+		t.Errorf("parseMagnetHash = %q, %v", h, err)
+	}
+	if _, err := parseMagnetHash("1", []byte(`{"msg": "no resource"}`)); !errors.Is(err, ErrMagnetUnavailable) {
+		t.Errorf("response without hash should be ErrMagnetUnavailable, got %v", err)
+	}
+}
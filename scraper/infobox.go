@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"github.com/tidwall/gjson"
+	"strings"
+)
+
+// InfoboxValue infobox 中的一个值，Label 为多值项中的小标题，例如 别名 下的 日文名
+type InfoboxValue struct {
+	Label string
+	Value string
+}
+
+// InfoboxEntry infobox 中的一项
+type InfoboxEntry struct {
+	Key    string
+	Values []InfoboxValue
+}
+
+// Infobox 按原顺序保存的完整 infobox
+type Infobox []InfoboxEntry
+
+// aliasKeys 保存作品其它名称的键
+var aliasKeys = []string{"中文名", "简体中文名", "别名", "日文名", "英文名", "罗马字"}
+
+// Add 添加一项，已有同名的键时追加到该项，重复的值只保留一个
+func (ib *Infobox) Add(key string, values ...InfoboxValue) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return
+	}
+	i := ib.index(key)
+	if i < 0 {
+		*ib = append(*ib, InfoboxEntry{Key: key})
+		i = len(*ib) - 1
+	}
+	entry := &(*ib)[i]
+	for _, v := range values {
+		v.Label, v.Value = strings.TrimSpace(v.Label), strings.TrimSpace(v.Value)
+		if v.Value == "" || entry.has(v.Value) {
+			continue
+		}
+		entry.Values = append(entry.Values, v)
+	}
+}
+
+func (ib Infobox) index(key string) int {
+	for i, entry := range ib {
+		if entry.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *InfoboxEntry) has(value string) bool {
+	for _, v := range e.Values {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings 该项的所有值
+func (e InfoboxEntry) Strings() []string {
+	values := make([]string, 0, len(e.Values))
+	for _, v := range e.Values {
+		values = append(values, v.Value)
+	}
+	return values
+}
+
+// Get 返回键对应的所有值，不存在时返回 nil
+func (ib Infobox) Get(key string) []string {
+	if i := ib.index(key); i >= 0 {
+		return ib[i].Strings()
+	}
+	return nil
+}
+
+// Lookup 按顺序返回第一个有值的键的所有值
+func (ib Infobox) Lookup(keys ...string) []string {
+	return ib.lookup(keys, false)
+}
+
+// lookup contains 为 true 时键包含即可，例如 开发 可以匹配 游戏开发商
+func (ib Infobox) lookup(keys []string, contains bool) []string {
+	for _, key := range keys {
+		for _, entry := range ib {
+			if len(entry.Values) > 0 && (entry.Key == key || (contains && strings.Contains(entry.Key, key))) {
+				return entry.Strings()
+			}
+		}
+	}
+	return nil
+}
+
+// First 按顺序返回第一个有值的键的第一个值
+func (ib Infobox) First(keys ...string) string {
+	return firstValue(ib.Lookup(keys...))
+}
+
+// Platform 平台，例如 PC、PS Vita
+func (ib Infobox) Platform() []string {
+	return ib.Lookup("平台", "platform")
+}
+
+// Aliases 中文名、别名等其它名称，去除重复
+func (ib Infobox) Aliases() []string {
+	var aliases []string
+	for _, key := range aliasKeys {
+		aliases = mergeStrings(aliases, ib.Get(key))
+	}
+	return aliases
+}
+
+// Website 官网
+func (ib Infobox) Website() string {
+	return ib.First("website", "官方网站", "官网", "HP")
+}
+
+// Price 售价，保留原文，例如 ¥9,800（税抜）
+func (ib Infobox) Price() string {
+	return ib.First("售价", "价格", "定价")
+}
+
+// mergeInfobox 将 src 中的项追加到 dst，已有的键合并值
+func mergeInfobox(dst, src Infobox) Infobox {
+	for _, entry := range src {
+		dst.Add(entry.Key, entry.Values...)
+	}
+	return dst
+}
+
+// parseBangumiInfobox 解析 bangumi 的 infobox，值可以是字符串或 [{"k": ..., "v": ...}] 形式的数组
+func parseBangumiInfobox(info gjson.Result) Infobox {
+	var ib Infobox
+	for _, entry := range info.Array() {
+		ib.Add(entry.Get("key").String(), parseBangumiInfoboxValue("", entry.Get("value"))...)
+	}
+	return ib
+}
+
+func parseBangumiInfoboxValue(label string, value gjson.Result) []InfoboxValue {
+	switch {
+	case value.IsArray():
+		var values []InfoboxValue
+		for _, v := range value.Array() {
+			values = append(values, parseBangumiInfoboxValue(label, v)...)
+		}
+		return values
+	case value.IsObject():
+		if k := value.Get("k").String(); k != "" {
+			label = k
+		}
+		return parseBangumiInfoboxValue(label, value.Get("v"))
+	case value.Exists():
+		return []InfoboxValue{{Label: label, Value: value.String()}}
+	}
+	return nil
+}
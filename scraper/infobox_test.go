@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBangumi_GetItemInfobox(t *testing.T) {
+	data := []byte(`{"infobox": [
+		{"key": "中文名", "value": "樱花，萌放。"},
+		{"key": "别名", "value": [{"v": "サクラノ詩"}, {"k": "英文名", "v": "Sakura no Uta"}, {"v": ""}]},
+		{"key": "平台", "value": [{"v": "PC"}, {"v": "PS4"}]},
+		{"key": "游戏类型", "value": "AVG"},
+		{"key": "售价", "value": "9,800円"},
+		{"key": "website", "value": "http://www.makura-soft.com/sakuuta/"},
+		{"key": "链接", "value": [{"k": "官网", "v": [{"v": "a"}, {"v": "b"}]}]}
+	]}`)
+	ib, err := BangumiScraper.GetItemInfobox(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, entry := range ib {
+		keys = append(keys, entry.Key)
+	}
+	if !reflect.DeepEqual(keys, []string{"中文名", "别名", "平台", "游戏类型", "售价", "website", "链接"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if want := []InfoboxValue{{Value: "サクラノ詩"}, {Label: "英文名", Value: "Sakura no Uta"}}; !reflect.DeepEqual(ib[1].Values, want) {
+		t.Errorf("unexpected aliases %+v", ib[1].Values)
+	}
+	if got := ib.Get("链接"); !reflect.DeepEqual(got, []string{"a", "b"}) || ib[6].Values[1].Label != "官网" {
+		t.Errorf("unexpected nested values %+v", ib[6].Values)
+	}
+	if got := ib.Platform(); !reflect.DeepEqual(got, []string{"PC", "PS4"}) {
+		t.Errorf("unexpected platform %v", got)
+	}
+	if got := ib.Aliases(); !reflect.DeepEqual(got, []string{"樱花，萌放。", "サクラノ詩", "Sakura no Uta"}) {
+		t.Errorf("unexpected aliases %v", got)
+	}
+	if ib.Website() != "http://www.makura-soft.com/sakuuta/" || ib.Price() != "9,800円" {
+		t.Errorf("unexpected website %q price %q", ib.Website(), ib.Price())
+	}
+}
+
+func TestInfobox_MergeAndDiff(t *testing.T) {
+	a := &Item{Infobox: Infobox{{Key: "平台", Values: []InfoboxValue{{Value: "PC"}}}}}
+	b := &Item{Infobox: Infobox{
+		{Key: "平台", Values: []InfoboxValue{{Value: "PC"}, {Value: "PS4"}}},
+		{Key: "售价", Values: []InfoboxValue{{Value: "9,800円"}}},
+	}}
+	merged := Merge(a, b)
+	if got := merged.Infobox.Get("平台"); !reflect.DeepEqual(got, []string{"PC", "PS4"}) || merged.Infobox.Price() != "9,800円" {
+		t.Errorf("unexpected merged infobox %+v", merged.Infobox)
+	}
+	if len(a.Infobox[0].Values) != 1 {
+		t.Errorf("merge modified source item %+v", a.Infobox)
+	}
+	changes := Diff(a, b)
+	if len(changes) != 2 || changes[0].Field != "Infobox" || changes[0].Kind != Added || changes[1].Kind != Changed {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
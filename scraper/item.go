@@ -1,13 +1,19 @@
 package scraper
 
+import "scraper/tools"
+
 type Category struct {
 	Identity string
 	Name     string
 }
 
 type TagItem struct {
-	Identity string
-	Name     string
+	Identity   string
+	Name       string
+	Spoiler    SpoilerLevel // 剧透程度
+	Sexual     bool         // 是否为色情内容
+	Count      int          // 打该标签的用户数，数据源不提供时为 0
+	Confidence float64      // 置信度 0-1，由用户数计算，Count 为 0 时无意义
 }
 
 type Tag struct {
@@ -20,26 +26,67 @@ type Character struct {
 	Introduction string
 	Avatar       string
 	Images       []string
+	VoiceActor   string            // 声优
+	Aliases      []string          // 别名，包括读音、中文名
+	Role         string            // 主角、配角 或 客串
+	Gender       string            // 性别，男 或 女
+	BloodType    string            // 血型
+	Birthday     string            // 生日，01-02 或 2006-01-02
+	Height       string            // 身高，例如 158cm
+	Measurements string            // 三围，例如 B82/W56/H84
+	Traits       map[string]string // 其它属性，例如 年龄、学年
+	SourceIDs    map[string]string // 各数据源的角色编号，source -> ID
+	Spoiler      SpoilerLevel      // 介绍的剧透程度
+	Sexual       bool              // 介绍或图片含色情内容
 }
 
 type Item struct {
-	proxy       string
-	Name        string      // 名称
-	Cover       string      // 封面
-	Preview     []string    // 预览图
-	Tags        []Tag       // 标签
-	Brand       string      // 品牌
-	ReleaseDate string      // 发售日
-	Link        string      // 官网
-	Information []string    // 介绍页面
-	SaveData    string      // 存档
-	WalkThrough string      // 攻略
-	Size        string      // 大小（仅供参考）
-	Magnet      string      // 磁力链接
-	BtFile      string      // bt 种子
-	OtherInfo   string      // 其它信息
-	Origin      string      // 来源网站
-	Character   []Character // 角色
-	Genre       []string    // 类别
-	Story       string      // 故事简介
+	proxy         string
+	Name          string            // 名称
+	OriginalTitle string            // 原名，通常为日文
+	Titles        map[string]string // 各语言的名称，语言 -> 名称，例如 zh -> 樱之诗
+	Aliases       []string          // 其它名称
+	Cover         string            // 封面
+	Preview       []string          // 预览图
+	Tags          []Tag             // 标签
+	Brand         string            // 品牌
+	BrandUri      string            // 品牌页面，可传给 GetBrand 获取品牌作品
+	ReleaseDate   string            // 发售日
+	Link          string            // 官网
+	Information   []string          // 介绍页面
+	SaveData      string            // 存档
+	WalkThrough   string            // 攻略
+	Size          string            // 大小（仅供参考）
+	SizeBytes     int64             // 大小（字节），由 Size 解析而来
+	SizeChecked   bool              // 是否已与种子内容比对过大小
+	SizeMatch     bool              // 大小是否与种子内容相符
+	Magnet        string            // 磁力链接
+	BtFile        string            // bt 种子
+	Torrent       *tools.Torrent    // bt 种子解析结果
+	OtherInfo     string            // 其它信息
+	Origin        string            // 来源网站
+	ExternalIDs   map[string]string // 各数据源的编号，source -> ID，例如 bangumi -> 226254
+	Character     []Character       // 角色
+	Staff         []Staff           // 制作人员
+	Relations     []Relation        // 关联条目，例如续集、FD
+	Genre         []string          // 类别
+	Story         string            // 故事简介
+	Infobox       Infobox           // 数据源给出的完整信息表，按原顺序保存
+	Kind          string            // 条目类型 game、anime、book、music、real，为空时视为 game
+	Anime         *AnimeInfo        // 动画、三次元条目的剧集信息
+	Book          *BookInfo         // 书籍条目的信息
+	Music         *MusicInfo        // 音乐条目的曲目信息
+}
+
+// sizeTolerance 网站显示大小与种子实际大小允许的相对误差
+const sizeTolerance = 0.05
+
+// VerifySize 将显示的大小与种子的实际总大小比对，并记录比对结果
+func (item *Item) VerifySize(total int64) bool {
+	if item.Size == "" || total <= 0 {
+		return false
+	}
+	item.SizeChecked = true
+	item.SizeMatch = tools.SizeMatch(item.Size, total, sizeTolerance)
+	return item.SizeMatch
 }
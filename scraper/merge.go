@@ -0,0 +1,180 @@
+package scraper
+
+import "strings"
+
+// Merge 合并多个数据源的 Item，排在前面的优先
+//
+// 字符串字段取第一个非空值，列表字段合并去重，角色按名称合并，制作人员按职务和姓名合并。
+func Merge(items ...*Item) *Item {
+	merged := &Item{}
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		mergeString(&merged.proxy, item.proxy)
+		mergeString(&merged.Name, item.Name)
+		mergeString(&merged.OriginalTitle, item.OriginalTitle)
+		mergeString(&merged.Cover, item.Cover)
+		mergeString(&merged.Brand, item.Brand)
+		mergeString(&merged.BrandUri, item.BrandUri)
+		mergeString(&merged.ReleaseDate, item.ReleaseDate)
+		mergeString(&merged.Link, item.Link)
+		mergeString(&merged.SaveData, item.SaveData)
+		mergeString(&merged.WalkThrough, item.WalkThrough)
+		mergeString(&merged.Size, item.Size)
+		mergeString(&merged.Magnet, item.Magnet)
+		mergeString(&merged.BtFile, item.BtFile)
+		mergeString(&merged.OtherInfo, item.OtherInfo)
+		mergeString(&merged.Origin, item.Origin)
+		mergeString(&merged.Story, item.Story)
+		mergeString(&merged.Kind, item.Kind)
+		if merged.SizeBytes == 0 {
+			merged.SizeBytes = item.SizeBytes
+		}
+		if merged.Torrent == nil {
+			merged.Torrent = item.Torrent
+			merged.SizeChecked, merged.SizeMatch = item.SizeChecked, item.SizeMatch
+		}
+		if merged.Anime == nil {
+			merged.Anime = item.Anime
+		}
+		if merged.Book == nil {
+			merged.Book = item.Book
+		}
+		if merged.Music == nil {
+			merged.Music = item.Music
+		}
+		merged.Preview = mergeStrings(merged.Preview, item.Preview)
+		merged.Information = mergeStrings(merged.Information, item.Information)
+		merged.Genre = mergeStrings(merged.Genre, item.Genre)
+		merged.Tags = mergeTags(merged.Tags, item.Tags)
+		merged.Infobox = mergeInfobox(merged.Infobox, item.Infobox)
+		merged.Character = mergeCharacters(merged.Character, item.Character)
+		for _, staff := range item.Staff {
+			merged.AddStaff(staff)
+		}
+		for _, relation := range item.Relations {
+			merged.AddRelation(relation)
+		}
+		for source, id := range item.ExternalIDs {
+			merged.AddExternalID(source, id)
+		}
+		for _, lang := range item.titleLangs() {
+			merged.AddTitle(lang, item.Titles[lang])
+		}
+		for _, alias := range item.Aliases {
+			merged.AddAlias(alias)
+		}
+	}
+	return merged
+}
+
+func mergeString(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+func mergeStrings(dst, src []string) []string {
+	seen := make(map[string]bool, len(dst))
+	for _, s := range dst {
+		seen[s] = true
+	}
+	for _, s := range src {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		dst = append(dst, s)
+	}
+	return dst
+}
+
+// appendUnique 追加去掉首尾空白的 s，为空、与 exclude 中的任意一个或已有元素相同时忽略
+func appendUnique(dst []string, s string, exclude ...string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return dst
+	}
+	for _, e := range exclude {
+		if e == s {
+			return dst
+		}
+	}
+	for _, e := range dst {
+		if e == s {
+			return dst
+		}
+	}
+	return append(dst, s)
+}
+
+func mergeTags(dst, src []Tag) []Tag {
+	for _, tag := range src {
+		i := 0
+		for ; i < len(dst); i++ {
+			if dst[i].Category == tag.Category {
+				break
+			}
+		}
+		if i == len(dst) {
+			dst = append(dst, Tag{Category: tag.Category})
+		}
+		for _, t := range tag.Item {
+			found := false
+			for j := range dst[i].Item {
+				if exist := &dst[i].Item[j]; exist.Identity == t.Identity {
+					// 剧透和色情标记取更严格的一方
+					if t.Spoiler > exist.Spoiler {
+						exist.Spoiler = t.Spoiler
+					}
+					exist.Sexual = exist.Sexual || t.Sexual
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst[i].Item = append(dst[i].Item, t)
+			}
+		}
+	}
+	return dst
+}
+
+func mergeCharacters(dst, src []Character) []Character {
+	for _, c := range src {
+		i := 0
+		for ; i < len(dst); i++ {
+			if dst[i].Name == c.Name {
+				break
+			}
+		}
+		if i == len(dst) {
+			dst = append(dst, Character{Name: c.Name})
+		}
+		mergeString(&dst[i].Introduction, c.Introduction)
+		mergeString(&dst[i].Avatar, c.Avatar)
+		mergeString(&dst[i].VoiceActor, c.VoiceActor)
+		mergeString(&dst[i].Role, c.Role)
+		mergeString(&dst[i].Gender, c.Gender)
+		mergeString(&dst[i].BloodType, c.BloodType)
+		mergeString(&dst[i].Birthday, c.Birthday)
+		mergeString(&dst[i].Height, c.Height)
+		mergeString(&dst[i].Measurements, c.Measurements)
+		if c.Spoiler > dst[i].Spoiler {
+			dst[i].Spoiler = c.Spoiler
+		}
+		dst[i].Sexual = dst[i].Sexual || c.Sexual
+		dst[i].Images = mergeStrings(dst[i].Images, c.Images)
+		for _, alias := range c.Aliases {
+			dst[i].AddAlias(alias)
+		}
+		for k, v := range c.Traits {
+			dst[i].SetTrait(k, v)
+		}
+		for source, id := range c.SourceIDs {
+			dst[i].AddSourceID(source, id)
+		}
+	}
+	return dst
+}
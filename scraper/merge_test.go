@@ -0,0 +1,35 @@
+package scraper
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	a := &Item{
+		Name:      "A",
+		Preview:   []string{"1", "2"},
+		Tags:      []Tag{{Item: []TagItem{{Identity: "x", Name: "x"}}}},
+		Character: []Character{{Name: "c1", Images: []string{"i1"}}},
+	}
+	b := &Item{
+		Name:      "B",
+		Brand:     "brand",
+		Preview:   []string{"2", "3"},
+		Tags:      []Tag{{Item: []TagItem{{Identity: "x", Name: "x"}, {Identity: "y", Name: "y"}}}},
+		Character: []Character{{Name: "c1", Avatar: "av", Images: []string{"i2"}}, {Name: "c2"}},
+	}
+	m := Merge(a, nil, b)
+	if m.Name != "A" || m.Brand != "brand" {
+		t.Errorf("unexpected scalar fields %+v", m)
+	}
+	if len(m.Preview) != 3 {
+		t.Errorf("unexpected previews %v", m.Preview)
+	}
+	if len(m.Tags) != 1 || len(m.Tags[0].Item) != 2 {
+		t.Errorf("unexpected tags %+v", m.Tags)
+	}
+	if len(m.Character) != 2 || m.Character[0].Avatar != "av" || len(m.Character[0].Images) != 2 {
+		t.Errorf("unexpected characters %+v", m.Character)
+	}
+	if len(a.Character[0].Images) != 1 {
+		t.Errorf("merge must not modify its inputs")
+	}
+}
@@ -0,0 +1,185 @@
+package scraper
+
+import (
+	"errors"
+	"strings"
+)
+
+type RelationKind string
+
+const (
+	RelationSequel     RelationKind = "sequel"     // 续集
+	RelationPrequel    RelationKind = "prequel"    // 前传
+	RelationFandisc    RelationKind = "fandisc"    // FD、扩展包
+	RelationParent     RelationKind = "parent"     // 本篇，FD 或衍生作品指向的原作
+	RelationSeries     RelationKind = "series"     // 同系列
+	RelationSpinoff    RelationKind = "spinoff"    // 衍生、外传
+	RelationVersion    RelationKind = "version"    // 不同版本，例如移植版、全年龄版
+	RelationAdaptation RelationKind = "adaptation" // 改编的动画、小说等其它类型作品
+	RelationOther      RelationKind = "other"
+)
+
+// bangumiRelations bangumi 条目关系名称 -> 关系类型
+var bangumiRelations = map[string]RelationKind{
+	"续集":    RelationSequel,
+	"前传":    RelationPrequel,
+	"扩展包":   RelationFandisc,
+	"FD":    RelationFandisc,
+	"番外篇":   RelationFandisc,
+	"资料片":   RelationFandisc,
+	"主线故事":  RelationParent,
+	"原作":    RelationParent,
+	"系列":    RelationSeries,
+	"同系列":   RelationSeries,
+	"相同世界观": RelationSeries,
+	"衍生":    RelationSpinoff,
+	"外传":    RelationSpinoff,
+	"角色出演":  RelationSpinoff,
+	"不同版本":  RelationVersion,
+	"不同演绎":  RelationVersion,
+	"合集":    RelationVersion,
+	"收录作品":  RelationVersion,
+	"动画":    RelationAdaptation,
+	"改编":    RelationAdaptation,
+	"书籍":    RelationAdaptation,
+	"游戏":    RelationAdaptation,
+	"音乐":    RelationAdaptation,
+	"原声集":   RelationAdaptation,
+	"片头曲":   RelationAdaptation,
+	"片尾曲":   RelationAdaptation,
+	"插入歌":   RelationAdaptation,
+}
+
+// seriesKinds 同一作品群的关系，解析系列时只沿这些关系扩展
+var seriesKinds = map[RelationKind]bool{
+	RelationSequel:  true,
+	RelationPrequel: true,
+	RelationFandisc: true,
+	RelationParent:  true,
+	RelationSeries:  true,
+	RelationSpinoff: true,
+	RelationVersion: true,
+}
+
+// Relation 关联条目
+type Relation struct {
+	Kind   RelationKind // 关系类型
+	Label  string       // 数据源给出的关系名称，例如 续集
+	Source string       // 来源
+	ID     string       // 关联条目在数据源中的编号
+	Name   string       // 关联条目名称
+	Uri    string       // 关联条目详情页地址
+}
+
+// NormalizeRelation 将 bangumi 的关系名称转为关系类型，未知的关系为 RelationOther
+func NormalizeRelation(label string) RelationKind {
+	if kind, ok := bangumiRelations[strings.TrimSpace(label)]; ok {
+		return kind
+	}
+	return RelationOther
+}
+
+// RelationsOf 返回指定类型的关联条目
+func (item *Item) RelationsOf(kind RelationKind) []Relation {
+	var relations []Relation
+	for _, r := range item.Relations {
+		if r.Kind == kind {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
+
+// AddRelation 记录关联条目，同一条目的同一种关系只记录一次
+func (item *Item) AddRelation(r Relation) {
+	if r.ID == "" && r.Uri == "" {
+		return
+	}
+	for _, exist := range item.Relations {
+		if exist.Kind == r.Kind && exist.Source == r.Source && exist.ID == r.ID && exist.Uri == r.Uri {
+			return
+		}
+	}
+	item.Relations = append(item.Relations, r)
+}
+
+// RelationFetcher 可以获取条目关联条目的数据源
+type RelationFetcher interface {
+	GetItemRelations(id string) ([]Relation, error)
+}
+
+// ErrSeriesTooLarge 系列条目数超过上限，返回的系列不完整
+var ErrSeriesTooLarge = errors.New("系列条目过多")
+
+// Series 由关联关系连在一起的一组条目
+type Series struct {
+	Root      string                // 起始条目编号
+	Members   []Relation            // 系列中的条目，按发现的顺序，第一个为起始条目
+	Relations map[string][]Relation // 条目编号 -> 该条目的关联条目
+}
+
+// SeriesResolver 从一个条目出发，沿续集、前传、FD 等关系找出整个系列
+type SeriesResolver struct {
+	Fetcher  RelationFetcher
+	Kinds    map[RelationKind]bool // 沿哪些关系扩展，为空时使用同一作品群的关系
+	MaxItems int                   // 最多抓取的条目数，为 0 时不限制
+}
+
+func NewSeriesResolver(fetcher RelationFetcher) *SeriesResolver {
+	return &SeriesResolver{Fetcher: fetcher, MaxItems: 100}
+}
+
+// Resolve 按广度优先抓取关联条目，name 为起始条目的名称
+func (r *SeriesResolver) Resolve(id, name string) (*Series, error) {
+	kinds := r.Kinds
+	if len(kinds) == 0 {
+		kinds = seriesKinds
+	}
+	series := &Series{
+		Root:      id,
+		Members:   []Relation{{ID: id, Name: name}},
+		Relations: make(map[string][]Relation),
+	}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(series.Members); i++ {
+		if r.MaxItems > 0 && i >= r.MaxItems {
+			return series, ErrSeriesTooLarge
+		}
+		current := series.Members[i].ID
+		relations, err := r.Fetcher.GetItemRelations(current)
+		if err != nil {
+			return series, err
+		}
+		series.Relations[current] = relations
+		for _, rel := range relations {
+			if !kinds[rel.Kind] || seen[rel.ID] {
+				continue
+			}
+			seen[rel.ID] = true
+			series.Members = append(series.Members, rel)
+		}
+	}
+	return series, nil
+}
+
+// Related 返回与条目有指定关系的系列成员，例如某个游戏的所有 FD
+func (s *Series) Related(id string, kind RelationKind) []Relation {
+	var relations []Relation
+	for _, rel := range s.Relations[id] {
+		if rel.Kind == kind {
+			relations = append(relations, rel)
+		}
+	}
+	return relations
+}
+
+// Missing 返回 has 判断为不存在的系列成员，用于检查库中缺少的条目
+func (s *Series) Missing(has func(id string) bool) []Relation {
+	var missing []Relation
+	for _, m := range s.Members {
+		if !has(m.ID) {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeRelations map[string][]Relation
+
+func (f fakeRelations) GetItemRelations(id string) ([]Relation, error) {
+	return f[id], nil
+}
+
+func rel(kind RelationKind, id string) Relation {
+	return Relation{Kind: kind, Source: "bangumi", ID: id, Name: "n" + id}
+}
+
+func TestParseBangumiRelations(t *testing.T) {
+	data := []byte(`[
+		{"id": 2, "type": 4, "name": "サクラノ刻", "relation": "续集"},
+		{"id": 3, "type": 2, "name": "", "name_cn": "动画", "relation": "动画"},
+		{"id": 4, "type": 4, "name": "FD", "relation": "扩展包"},
+		{"id": 4, "type": 4, "name": "FD", "relation": "扩展包"}
+	]`)
+	relations := parseBangumiRelations(data)
+	if len(relations) != 3 {
+		t.Fatalf("unexpected relations %+v", relations)
+	}
+	if r := relations[0]; r.Kind != RelationSequel || r.Label != "续集" || r.Uri != "https://api.bgm.tv/v0/subjects/2" {
+		t.Errorf("unexpected relation %+v", r)
+	}
+	if r := relations[1]; r.Kind != RelationAdaptation || r.Name != "动画" {
+		t.Errorf("unexpected relation %+v", r)
+	}
+	item := &Item{Relations: relations}
+	if fds := item.RelationsOf(RelationFandisc); len(fds) != 1 || fds[0].ID != "4" {
+		t.Errorf("unexpected fandiscs %+v", fds)
+	}
+}
+
+func TestSeriesResolver_Resolve(t *testing.T) {
+	fetcher := fakeRelations{
+		"1": {rel(RelationSequel, "2"), rel(RelationFandisc, "3"), rel(RelationAdaptation, "9")},
+		"2": {rel(RelationPrequel, "1"), rel(RelationFandisc, "4")},
+		"3": {rel(RelationParent, "1")},
+		"4": {rel(RelationParent, "2")},
+	}
+	series, err := NewSeriesResolver(fetcher).Resolve("1", "base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range series.Members {
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 4 || ids[0] != "1" || ids[1] != "2" || ids[2] != "3" || ids[3] != "4" {
+		t.Errorf("unexpected members %v", ids)
+	}
+	if fds := series.Related("2", RelationFandisc); len(fds) != 1 || fds[0].ID != "4" {
+		t.Errorf("unexpected fandiscs %+v", fds)
+	}
+	missing := series.Missing(func(id string) bool { return id == "1" || id == "2" })
+	if len(missing) != 2 || missing[0].ID != "3" || missing[1].ID != "4" {
+		t.Errorf("unexpected missing %+v", missing)
+	}
+
+	r := NewSeriesResolver(fetcher)
+	r.MaxItems = 2
+	if _, err = r.Resolve("1", "base"); !errors.Is(err, ErrSeriesTooLarge) {
+		t.Errorf("expected ErrSeriesTooLarge, got %v", err)
+	}
+}
+
+func TestDiff_Relations(t *testing.T) {
+	old := &Item{Relations: []Relation{rel(RelationSequel, "2")}}
+	new := &Item{Relations: []Relation{rel(RelationSequel, "2"), rel(RelationFandisc, "3")}}
+	changes := Diff(old, new).Field("Relations")
+	if len(changes) != 1 || changes[0].Key != "fandisc/bangumi:3" || changes[0].Kind != Added {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	if m := Merge(old, new); len(m.Relations) != 2 {
+		t.Errorf("unexpected merged relations %+v", m.Relations)
+	}
+}
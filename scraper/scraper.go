@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"sort"
+	"sync"
+)
+
+// Scraper 可以抓取详情页的数据源
+type Scraper interface {
+	GetItem(uri string) (*Item, error)
+}
+
+// Searcher 支持按关键词搜索的数据源
+type Searcher interface {
+	Search(keyword string) ([]SearchResult, error)
+}
+
+// SearchResult 搜索结果
+type SearchResult struct {
+	Source      string // 来源
+	Name        string // 名称
+	Brand       string // 品牌
+	ReleaseDate string // 发售日
+	Uri         string // 详情页地址，可直接传给 GetItem
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]Scraper)
+)
+
+// Register 注册数据源，同名数据源会被覆盖
+func Register(name string, s Scraper) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = s
+}
+
+// Lookup 按名称查找已注册的数据源
+func Lookup(name string) (Scraper, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Sources 返回所有已注册数据源的名称
+func Sources() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Searchers 返回所有支持搜索的数据源
+func Searchers() map[string]Searcher {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	searchers := make(map[string]Searcher)
+	for name, s := range registry {
+		if searcher, ok := s.(Searcher); ok {
+			searchers[name] = searcher
+		}
+	}
+	return searchers
+}
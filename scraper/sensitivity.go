@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SpoilerLevel 剧透程度，与 VNDB 相同
+type SpoilerLevel int
+
+const (
+	SpoilerNone  SpoilerLevel = 0 // 无剧透
+	SpoilerMinor SpoilerLevel = 1 // 轻微剧透
+	SpoilerMajor SpoilerLevel = 2 // 严重剧透
+)
+
+// bangumiMaskRe bangumi 用 [mask] 标记剧透内容
+var bangumiMaskRe = regexp.MustCompile(`(?s)\[mask\].*?\[/mask\]`)
+
+// Filter 过滤 Item 中的剧透和色情内容，零值只隐藏剧透
+type Filter struct {
+	MaxSpoiler    SpoilerLevel // 保留的最高剧透程度
+	HideSexual    bool         // 隐藏色情标签，以及角色的色情介绍和图片
+	MinConfidence float64      // 有投票数的标签置信度低于该值时隐藏
+}
+
+// ShowAll 不过滤任何内容
+var ShowAll = Filter{MaxSpoiler: SpoilerMajor}
+
+// Allow 标签是否可以展示
+func (f Filter) Allow(tag TagItem) bool {
+	if tag.Spoiler > f.MaxSpoiler || (f.HideSexual && tag.Sexual) {
+		return false
+	}
+	return tag.Count == 0 || tag.Confidence >= f.MinConfidence
+}
+
+// Filtered 返回过滤后的副本，不修改原 Item
+//
+// 剧透程度超出的角色介绍会去掉 [mask] 标记的部分，没有标记时整段去掉。
+func (item *Item) Filtered(f Filter) *Item {
+	filtered := *item
+	filtered.Tags = nil
+	for _, tag := range item.Tags {
+		t := Tag{Category: tag.Category}
+		for _, ti := range tag.Item {
+			if f.Allow(ti) {
+				t.Item = append(t.Item, ti)
+			}
+		}
+		if len(t.Item) > 0 {
+			filtered.Tags = append(filtered.Tags, t)
+		}
+	}
+	filtered.Character = nil
+	for _, c := range item.Character {
+		if c.Spoiler > f.MaxSpoiler {
+			c.Introduction = StripSpoilers(c.Introduction)
+		}
+		if f.HideSexual && c.Sexual {
+			c.Introduction, c.Images = "", nil
+		}
+		filtered.Character = append(filtered.Character, c)
+	}
+	return &filtered
+}
+
+// StripSpoilers 去掉 [mask] 标记的剧透内容，没有标记时无法区分，返回空字符串
+func StripSpoilers(s string) string {
+	if !bangumiMaskRe.MatchString(s) {
+		return ""
+	}
+	return strings.TrimSpace(bangumiMaskRe.ReplaceAllString(s, ""))
+}
+
+// setConfidence 按投票数计算置信度，票数最多的标签为 1
+func setConfidence(tags []TagItem) {
+	max := 0
+	for _, t := range tags {
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+	if max == 0 {
+		return
+	}
+	for i := range tags {
+		tags[i].Confidence = float64(tags[i].Count) / float64(max)
+	}
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"github.com/tidwall/gjson"
+	"reflect"
+	"testing"
+)
+
+func sensitiveItem() *Item {
+	return &Item{
+		Tags: []Tag{
+			{Item: []TagItem{
+				{Identity: "adv", Name: "ADV", Count: 100, Confidence: 1},
+				{Identity: "twist", Name: "叙述诡计", Spoiler: SpoilerMajor, Count: 40, Confidence: 0.4},
+				{Identity: "rare", Name: "冷门", Count: 2, Confidence: 0.02},
+			}},
+			{Category: Category{Identity: "h"}, Item: []TagItem{{Identity: "nukige", Name: "拔作", Sexual: true}}},
+		},
+		Character: []Character{
+			{Name: "藍", Introduction: "美术部的学姐。[mask]其实是……[/mask]", Spoiler: SpoilerMinor},
+			{Name: "雫", Introduction: "全部都是剧透", Spoiler: SpoilerMajor},
+			{Name: "里", Introduction: "H", Images: []string{"h.jpg"}, Sexual: true},
+		},
+	}
+}
+
+func tagIdentities(item *Item) []string {
+	var ids []string
+	for _, tag := range item.Tags {
+		for _, t := range tag.Item {
+			ids = append(ids, t.Identity)
+		}
+	}
+	return ids
+}
+
+func TestItem_Filtered(t *testing.T) {
+	item := sensitiveItem()
+
+	// 零值只隐藏剧透
+	filtered := item.Filtered(Filter{})
+	if got := tagIdentities(filtered); !reflect.DeepEqual(got, []string{"adv", "rare", "nukige"}) {
+		t.Errorf("default filter tags = %v", got)
+	}
+	if c := filtered.Character; c[0].Introduction != "美术部的学姐。" || c[1].Introduction != "" || c[2].Introduction != "H" {
+		t.Errorf("default filter characters = %+v", c)
+	}
+	if len(tagIdentities(item)) != 4 || item.Character[1].Introduction == "" {
+		t.Errorf("Filtered should not modify the original item")
+	}
+
+	filtered = item.Filtered(Filter{MaxSpoiler: SpoilerMajor, HideSexual: true, MinConfidence: 0.1})
+	if got := tagIdentities(filtered); !reflect.DeepEqual(got, []string{"adv", "twist"}) || len(filtered.Tags) != 1 {
+		t.Errorf("strict filter tags = %v", got)
+	}
+	if c := filtered.Character[2]; c.Introduction != "" || c.Images != nil {
+		t.Errorf("sexual character should be hidden, got %+v", c)
+	}
+	if got := item.Filtered(ShowAll); !reflect.DeepEqual(got, item) {
+		t.Errorf("ShowAll should keep everything, got %+v", got)
+	}
+}
+
+func TestBangumi_TagConfidence(t *testing.T) {
+	tags, _ := BangumiScraper.GetItemTags([]byte(`{"tags": [
+		{"name": "ADV", "count": 200}, {"name": "枕", "count": 50}, {"name": "冷门", "count": 0}]}`))
+	got := tags[0].Item
+	if got[0].Count != 200 || got[0].Confidence != 1 || got[1].Confidence != 0.25 || got[2].Confidence != 0 {
+		t.Errorf("unexpected tags %+v", got)
+	}
+	if (Filter{MinConfidence: 0.5}).Allow(got[1]) {
+		t.Errorf("low confidence tag should be hidden")
+	}
+}
+
+func TestSensitivity_Sources(t *testing.T) {
+	c := parseBangumiCharacter(gjson.Parse(`{"relation": "主角"}`), []byte(`{"id": 1, "name": "藍", "summary": "学姐。[mask]真相[/mask]"}`))
+	if c.Spoiler != SpoilerMinor {
+		t.Errorf("masked summary should be a spoiler, got %+v", c)
+	}
+
+	merged := Merge(
+		&Item{Tags: []Tag{{Item: []TagItem{{Identity: "twist"}}}}, Character: []Character{{Name: "藍"}}},
+		&Item{Tags: []Tag{{Item: []TagItem{{Identity: "twist", Spoiler: SpoilerMajor, Sexual: true}}}}, Character: []Character{c}},
+	)
+	if tag := merged.Tags[0].Item[0]; tag.Spoiler != SpoilerMajor || !tag.Sexual || merged.Character[0].Spoiler != SpoilerMinor {
+		t.Errorf("merge should keep the stricter flags, got %+v %+v", merged.Tags, merged.Character)
+	}
+
+	taxonomy := &Taxonomy{Categories: []TagCategory{{Identity: "plot", Tags: []CanonicalTag{
+		{Identity: "twist", Name: "叙述诡计", Spoiler: SpoilerMajor, Aliases: []string{"反转"}},
+	}}}}
+	if err := taxonomy.Build(); err != nil {
+		t.Fatal(err)
+	}
+	_, tag, ok := taxonomy.Lookup("bangumi", TagItem{Identity: "反转", Name: "反转", Count: 10})
+	if !ok || tag.Spoiler != SpoilerMajor || tag.Count != 10 {
+		t.Errorf("taxonomy should set the spoiler level, got %+v", tag)
+	}
+}
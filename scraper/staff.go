@@ -0,0 +1,111 @@
+package scraper
+
+import "strings"
+
+// Staff 制作人员
+type Staff struct {
+	Role      string            // 职务，统一为 bangumi 的中文名称，例如 原画、剧本、音乐
+	Name      string            // 姓名
+	SourceIDs map[string]string // 各数据源的人物编号，source -> ID
+}
+
+// staffRoles 各数据源的职务名称 -> 统一的职务名称
+var staffRoles = map[string]string{
+	"原画":         "原画",
+	"原画師":        "原画",
+	"キャラクターデザイン": "人物设定",
+	"人物设定":       "人物设定",
+	"シナリオ":       "剧本",
+	"剧本":         "剧本",
+	"脚本":         "剧本",
+	"音楽":         "音乐",
+	"音乐":         "音乐",
+	"作曲":         "音乐",
+	"主題歌":        "主题歌演出",
+	"歌":          "主题歌演出",
+	"主题歌演出":      "主题歌演出",
+	"作詞":         "主题歌作词",
+	"主题歌作词":      "主题歌作词",
+	"主题歌作曲":      "主题歌作曲",
+	"ディレクター":     "导演",
+	"監督":         "导演",
+	"导演":         "导演",
+	"プロデューサー":    "制作人",
+	"制作人":        "制作人",
+	"企画":         "企画",
+	"美術":         "美术",
+	"美术":         "美术",
+	"背景":         "美术",
+	"プログラム":      "程序",
+	"程序":         "程序",
+	"彩色":         "CG",
+	"CG":         "CG",
+	"SD原画":       "SD原画",
+	"声優":         "声优",
+	"声优":         "声优",
+}
+
+// NormalizeRole 将各数据源的职务名称统一，未知的职务原样返回
+func NormalizeRole(role string) string {
+	role = trimRole(role)
+	if r, ok := staffRoles[role]; ok {
+		return r
+	}
+	return role
+}
+
+// isStaffRole 职务名称是否为已知的制作人员职务
+func isStaffRole(role string) bool {
+	_, ok := staffRoles[trimRole(role)]
+	return ok
+}
+
+func trimRole(role string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(role), ":："))
+}
+
+// splitNames 拆分 “甲、乙” 形式的多个姓名
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == '、' || r == '，' || r == ',' || r == '\n'
+	}) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// AddStaff 记录制作人员，同一职务的同名人员会合并编号
+func (item *Item) AddStaff(s Staff) {
+	s.Role, s.Name = NormalizeRole(s.Role), strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return
+	}
+	for i := range item.Staff {
+		if item.Staff[i].Role == s.Role && item.Staff[i].Name == s.Name {
+			for source, id := range s.SourceIDs {
+				addID(&item.Staff[i].SourceIDs, source, id)
+			}
+			return
+		}
+	}
+	staff := Staff{Role: s.Role, Name: s.Name}
+	for source, id := range s.SourceIDs {
+		addID(&staff.SourceIDs, source, id)
+	}
+	item.Staff = append(item.Staff, staff)
+}
+
+// StaffByRole 返回指定职务的制作人员姓名
+func (item *Item) StaffByRole(role string) []string {
+	role = NormalizeRole(role)
+	var names []string
+	for _, s := range item.Staff {
+		if s.Role == role {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"bytes"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"testing"
+)
+
+func TestItem_AddStaff(t *testing.T) {
+	item := &Item{}
+	item.AddStaff(Staff{Role: "シナリオ：", Name: "すかぢ"})
+	item.AddStaff(Staff{Role: "剧本", Name: "すかぢ", SourceIDs: map[string]string{"bangumi": "1"}})
+	item.AddStaff(Staff{Role: "原画", Name: " "})
+	if len(item.Staff) != 1 || item.Staff[0].Role != "剧本" || item.Staff[0].SourceIDs["bangumi"] != "1" {
+		t.Errorf("unexpected staff %+v", item.Staff)
+	}
+	if names := item.StaffByRole("シナリオ"); len(names) != 1 || names[0] != "すかぢ" {
+		t.Errorf("unexpected StaffByRole %v", names)
+	}
+
+	m := Merge(&Item{Staff: []Staff{{Role: "原画", Name: "A"}}}, item)
+	if len(m.Staff) != 2 {
+		t.Errorf("unexpected merged staff %+v", m.Staff)
+	}
+	changes := Diff(item, m).Field("Staff")
+	if len(changes) != 1 || changes[0].Key != "原画/A" || changes[0].Kind != Added {
+		t.Errorf("unexpected staff changes %v", changes)
+	}
+}
+
+func TestBangumi_GetItemStaff(t *testing.T) {
+	data := []byte(`{"infobox": [
+		{"key": "剧本", "value": "すかぢ、B"},
+		{"key": "原画", "value": [{"v": "籠目"}]},
+		{"key": "游戏类型", "value": "ADV"}
+	]}`)
+	persons := []byte(`[
+		{"id": 1, "name": "すかぢ", "type": 1, "relation": "剧本"},
+		{"id": 2, "name": "枕", "type": 2, "relation": "开发"}
+	]`)
+	staff, err := BangumiScraper.GetItemStaff(data, persons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staff) != 3 {
+		t.Fatalf("unexpected staff %+v", staff)
+	}
+	if staff[0].Name != "すかぢ" || staff[0].SourceIDs["bangumi"] != "1" {
+		t.Errorf("person should keep its id: %+v", staff[0])
+	}
+	if staff[1].Role != "剧本" || staff[1].Name != "B" || staff[2].Role != "原画" || staff[2].Name != "籠目" {
+		t.Errorf("unexpected infobox staff %+v", staff[1:])
+	}
+}
+
+func TestGetChu_GetItemStaff(t *testing.T) {
+	html := `<html><body><table id="soft_table">
+		<tr><td>ブランド：</td><td><a href="#">枕</a></td></tr>
+		<tr><td>原画：</td><td><a href="#">籠目</a>、<a href="#">基4%</a></td></tr>
+		<tr><td>シナリオ：</td><td>すかぢ、B</td></tr>
+	</table></body></html>`
+	encoded, _, err := transform.String(japanese.EUCJP.NewEncoder(), html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := goquery.NewDocumentFromReader(bytes.NewBufferString(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staff, err := GetChuScraper.GetItemStaff(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Staff{{Role: "原画", Name: "籠目"}, {Role: "原画", Name: "基4%"}, {Role: "剧本", Name: "すかぢ"}, {Role: "剧本", Name: "B"}}
+	if len(staff) != len(want) {
+		t.Fatalf("unexpected staff %+v", staff)
+	}
+	for i := range want {
+		if staff[i].Role != want[i].Role || staff[i].Name != want[i].Name {
+			t.Errorf("staff[%d] = %+v, want %+v", i, staff[i], want[i])
+		}
+	}
+}
+
+func TestSplitCharaName(t *testing.T) {
+	cases := []struct{ in, name, cv string }{
+		{"夏目 藍（なつめ あい） CV：遠野そよぎ", "夏目 藍（なつめ あい）", "遠野そよぎ"},
+		{"鳥谷 真琴 (CV:小倉結衣)", "鳥谷 真琴", "小倉結衣"},
+		{"草薙 直哉", "草薙 直哉", ""},
+	}
+	for _, c := range cases {
+		if name, cv := splitCharaName(c.in); name != c.name || cv != c.cv {
+			t.Errorf("splitCharaName(%q) = %q, %q", c.in, name, cv)
+		}
+	}
+}
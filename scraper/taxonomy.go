@@ -0,0 +1,175 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"os"
+	"scraper/tools"
+	"strings"
+)
+
+// CanonicalTag 统一后的标签
+type CanonicalTag struct {
+	Identity string       `json:"identity"`
+	Name     string       `json:"name"`
+	Aliases  []string     `json:"aliases"` // 各数据源通用的其它写法，例如 泣き、催泪
+	Spoiler  SpoilerLevel `json:"spoiler"` // 剧透程度，数据源给出的更高时以数据源为准
+	Sexual   bool         `json:"sexual"`  // 是否为色情内容
+}
+
+// TagCategory 统一后的标签分类
+type TagCategory struct {
+	Identity string         `json:"identity"`
+	Name     string         `json:"name"`
+	Tags     []CanonicalTag `json:"tags"`
+}
+
+// Taxonomy 统一的标签体系，以及各数据源标签到统一标签的映射
+//
+// 映射文件为 JSON，例如：
+//
+//	{
+//	  "categories": [{"identity": "genre", "name": "类型", "tags": [
+//	    {"identity": "nakige", "name": "泣きゲー", "aliases": ["泣き", "催泪"]},
+//	    {"identity": "twist", "name": "叙述诡计", "spoiler": 2}
+//	  ]}],
+//	  "sources": {"ggbases": {"crying": "nakige"}}
+//	}
+//
+// sources 中的键为数据源标签的标识或名称，值为统一标签的标识。
+type Taxonomy struct {
+	Categories   []TagCategory                `json:"categories"`
+	Sources      map[string]map[string]string `json:"sources"`       // 数据源 -> 标签标识或名称 -> 统一标签标识
+	DropUnmapped bool                         `json:"drop_unmapped"` // 丢弃无法映射的标签，默认保留在原分类中
+
+	tags    map[string]taxonomyEntry            // 统一标签的标识、名称、别名 -> 统一标签
+	sources map[string]map[string]taxonomyEntry // 数据源 -> 标签 -> 统一标签
+}
+
+type taxonomyEntry struct {
+	category int // 在 Categories 中的下标
+	tag      CanonicalTag
+}
+
+// LoadTaxonomy 读取 JSON 格式的标签映射文件
+func LoadTaxonomy(name string) (*Taxonomy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t := &Taxonomy{}
+	if err = json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("解析标签映射文件 %s 失败: %w", name, err)
+	}
+	if err = t.Build(); err != nil {
+		return nil, fmt.Errorf("标签映射文件 %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// tagKey 比较标签时忽略全半角、大小写和首尾空白
+func tagKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFKC.String(s)))
+}
+
+// Build 检查并建立索引，LoadTaxonomy 会自动调用，直接构造或修改 Categories、Sources 后需要调用
+func (t *Taxonomy) Build() error {
+	t.tags = make(map[string]taxonomyEntry)
+	identities := make(map[string]taxonomyEntry)
+	for i, c := range t.Categories {
+		if c.Identity == "" {
+			return fmt.Errorf("第 %d 个分类缺少标识", i+1)
+		}
+		for _, tag := range c.Tags {
+			if tag.Identity == "" {
+				return fmt.Errorf("分类 %s 中有标签缺少标识", c.Identity)
+			}
+			if tag.Name == "" {
+				tag.Name = tag.Identity
+			}
+			if _, ok := identities[tag.Identity]; ok {
+				return fmt.Errorf("标签 %s 重复", tag.Identity)
+			}
+			entry := taxonomyEntry{category: i, tag: tag}
+			identities[tag.Identity] = entry
+			for _, key := range append([]string{tag.Identity, tag.Name}, tag.Aliases...) {
+				if _, ok := t.tags[tagKey(key)]; !ok {
+					t.tags[tagKey(key)] = entry
+				}
+			}
+		}
+	}
+	t.sources = make(map[string]map[string]taxonomyEntry, len(t.Sources))
+	for source, mapping := range t.Sources {
+		t.sources[source] = make(map[string]taxonomyEntry, len(mapping))
+		for from, to := range mapping {
+			entry, ok := identities[to]
+			if !ok {
+				return fmt.Errorf("数据源 %s 的标签 %s 映射到了不存在的标签 %s", source, from, to)
+			}
+			t.sources[source][tagKey(from)] = entry
+		}
+	}
+	return nil
+}
+
+// Lookup 查找数据源标签对应的统一标签，先查数据源的映射，再按统一标签的标识、名称和别名匹配
+func (t *Taxonomy) Lookup(source string, tag TagItem) (Category, TagItem, bool) {
+	for _, key := range []string{tag.Identity, tag.Name} {
+		if key == "" {
+			continue
+		}
+		entry, ok := t.sources[source][tagKey(key)]
+		if !ok {
+			entry, ok = t.tags[tagKey(key)]
+		}
+		if ok {
+			c := t.Categories[entry.category]
+			tag.Identity, tag.Name = entry.tag.Identity, entry.tag.Name
+			if entry.tag.Spoiler > tag.Spoiler {
+				tag.Spoiler = entry.tag.Spoiler
+			}
+			tag.Sexual = tag.Sexual || entry.tag.Sexual
+			return Category{Identity: c.Identity, Name: c.Name}, tag, true
+		}
+	}
+	return Category{}, TagItem{}, false
+}
+
+// Normalize 将数据源的标签转为统一标签，按映射文件中分类的顺序排列，无法映射的标签保留在原分类中
+func (t *Taxonomy) Normalize(source string, tags []Tag) []Tag {
+	var mapped, unmapped []Tag
+	for _, tag := range tags {
+		for _, item := range tag.Item {
+			category, canonical, ok := t.Lookup(source, item)
+			if ok {
+				mapped = mergeTags(mapped, []Tag{{Category: category, Item: []TagItem{canonical}}})
+			} else if !t.DropUnmapped {
+				unmapped = mergeTags(unmapped, []Tag{{Category: tag.Category, Item: []TagItem{item}}})
+			}
+		}
+	}
+	// mergeTags 按出现顺序追加分类，这里改为映射文件中的顺序
+	var normalized []Tag
+	for _, c := range t.Categories {
+		for _, tag := range mapped {
+			if tag.Category.Identity == c.Identity {
+				normalized = append(normalized, tag)
+			}
+		}
+	}
+	return append(normalized, unmapped...)
+}
+
+// NormalizeItem 按 Item.Origin 识别数据源并统一标签，应在合并多个数据源的结果之前调用
+func (t *Taxonomy) NormalizeItem(item *Item) {
+	if item == nil {
+		return
+	}
+	source := ""
+	if code, ok := tools.ParseCode(item.Origin); ok {
+		source = code.Source
+	}
+	item.Tags = t.Normalize(source, item.Tags)
+}
@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTaxonomy = `{
+	"categories": [
+		{"identity": "genre", "name": "类型", "tags": [
+			{"identity": "adv", "name": "ADV"},
+			{"identity": "nakige", "name": "泣きゲー", "aliases": ["泣き", "催泪"]}
+		]},
+		{"identity": "setting", "name": "背景", "tags": [
+			{"identity": "school", "name": "学园", "aliases": ["学園もの"]}
+		]}
+	],
+	"sources": {
+		"ggbases": {"crying": "nakige", "school life": "school"}
+	}
+}`
+
+func loadTestTaxonomy(t *testing.T, content string) (*Taxonomy, error) {
+	name := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadTaxonomy(name)
+}
+
+func TestTaxonomy_Normalize(t *testing.T) {
+	taxonomy, err := loadTestTaxonomy(t, testTaxonomy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bangumi 的标签没有分类，按名称和别名匹配
+	bangumi := &Item{
+		Origin: "https://api.bgm.tv/v0/subjects/123",
+		Tags: []Tag{{Item: []TagItem{
+			{Identity: "学園もの", Name: "学園もの"},
+			{Identity: "催泪", Name: "催泪"},
+			{Identity: "ＡＤＶ", Name: "ＡＤＶ"},
+			{Identity: "枕", Name: "枕"},
+		}}},
+	}
+	taxonomy.NormalizeItem(bangumi)
+	want := []Tag{
+		{Category: Category{Identity: "genre", Name: "类型"}, Item: []TagItem{{Identity: "nakige", Name: "泣きゲー"}, {Identity: "adv", Name: "ADV"}}},
+		{Category: Category{Identity: "setting", Name: "背景"}, Item: []TagItem{{Identity: "school", Name: "学园"}}},
+		{Item: []TagItem{{Identity: "枕", Name: "枕"}}},
+	}
+	if !reflect.DeepEqual(bangumi.Tags, want) {
+		t.Errorf("unexpected bangumi tags %+v", bangumi.Tags)
+	}
+
+	// ggbases 按数据源的映射匹配，映射只对该数据源生效
+	tags := []Tag{{Category: Category{Name: "female"}, Item: []TagItem{
+		{Identity: "crying", Name: "crying"},
+		{Identity: "School Life", Name: "school life"},
+	}}}
+	if got := taxonomy.Normalize("ggbases", tags); len(got) != 2 || got[0].Item[0].Identity != "nakige" || got[1].Item[0].Identity != "school" {
+		t.Errorf("unexpected ggbases tags %+v", got)
+	}
+	if got := taxonomy.Normalize("2dfan", tags); !reflect.DeepEqual(got, tags) {
+		t.Errorf("mapping should only apply to ggbases, got %+v", got)
+	}
+
+	taxonomy.DropUnmapped = true
+	if got := taxonomy.Normalize("2dfan", tags); len(got) != 0 {
+		t.Errorf("unmapped tags should be dropped, got %+v", got)
+	}
+	// 已统一的标签再次统一结果不变
+	taxonomy.DropUnmapped = false
+	normalized := taxonomy.Normalize("", want)
+	if !reflect.DeepEqual(normalized, want) {
+		t.Errorf("normalize is not idempotent: %+v", normalized)
+	}
+}
+
+func TestLoadTaxonomy_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"categories": [{"identity": "genre", "tags": [{"identity": "adv"}, {"identity": "adv"}]}]}`,
+		`{"categories": [{"identity": "genre", "tags": [{"identity": "adv"}]}], "sources": {"ggbases": {"x": "missing"}}}`,
+		`{"categories": [{"name": "类型"}]}`,
+		`not json`,
+	} {
+		if _, err := loadTestTaxonomy(t, content); err == nil || !strings.Contains(err.Error(), "tags.json") {
+			t.Errorf("LoadTaxonomy(%s) err = %v", content, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 名称的语言
+const (
+	LangJa = "ja"
+	LangZh = "zh"
+	LangEn = "en"
+)
+
+// GuessLanguage 根据文字猜测名称的语言，含假名为日文，含汉字为中文，只有拉丁字母为英文，无法判断时返回空字符串
+func GuessLanguage(s string) string {
+	var han, latin bool
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return LangJa
+		case unicode.Is(unicode.Han, r):
+			han = true
+		case unicode.Is(unicode.Latin, r):
+			latin = true
+		}
+	}
+	switch {
+	case han:
+		return LangZh
+	case latin:
+		return LangEn
+	}
+	return ""
+}
+
+// AddTitle 记录某种语言的名称，该语言已有其它名称时作为别名
+func (item *Item) AddTitle(lang, title string) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return
+	}
+	if lang == "" {
+		item.AddAlias(title)
+		return
+	}
+	if item.Titles == nil {
+		item.Titles = make(map[string]string)
+	}
+	switch exist := item.Titles[lang]; exist {
+	case "":
+		item.Titles[lang] = title
+		item.removeAlias(title)
+	case title:
+	default:
+		item.AddAlias(title)
+	}
+}
+
+// AddAlias 记录别名，与名称、原名或各语言名称相同时忽略
+func (item *Item) AddAlias(alias string) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || alias == item.Name || alias == item.OriginalTitle {
+		return
+	}
+	for _, title := range item.Titles {
+		if title == alias {
+			return
+		}
+	}
+	for _, exist := range item.Aliases {
+		if exist == alias {
+			return
+		}
+	}
+	item.Aliases = append(item.Aliases, alias)
+}
+
+func (item *Item) removeAlias(title string) {
+	for i, alias := range item.Aliases {
+		if alias == title {
+			item.Aliases = append(item.Aliases[:i:i], item.Aliases[i+1:]...)
+			return
+		}
+	}
+}
+
+// Title 按偏好的语言顺序选择展示的名称，都没有时依次使用原名和 Name
+func (item *Item) Title(langs ...string) string {
+	for _, lang := range langs {
+		if title := item.Titles[lang]; title != "" {
+			return title
+		}
+	}
+	if item.OriginalTitle != "" {
+		return item.OriginalTitle
+	}
+	return item.Name
+}
+
+// AllTitles 原名、名称、各语言名称和别名，去除重复，用于在其它数据源中搜索
+func (item *Item) AllTitles() []string {
+	titles := mergeStrings(nil, []string{item.OriginalTitle, item.Name})
+	for _, lang := range item.titleLangs() {
+		titles = mergeStrings(titles, []string{item.Titles[lang]})
+	}
+	return mergeStrings(titles, item.Aliases)
+}
+
+// titleLangs 已有名称的语言，按字母排序
+func (item *Item) titleLangs() []string {
+	langs := make([]string, 0, len(item.Titles))
+	for lang := range item.Titles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
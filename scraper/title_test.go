@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGuessLanguage(t *testing.T) {
+	for s, want := range map[string]string{
+		"サクラノ詩":         LangJa,
+		"樱之诗":           LangZh,
+		"Sakura no Uta": LangEn,
+		"2015":          "",
+	} {
+		if got := GuessLanguage(s); got != want {
+			t.Errorf("GuessLanguage(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestItem_Titles(t *testing.T) {
+	item := &Item{Name: "サクラノ詩", OriginalTitle: "サクラノ詩"}
+	item.AddAlias("樱之诗")
+	item.AddTitle(LangJa, "サクラノ詩")
+	item.AddTitle(LangZh, "樱之诗")
+	item.AddTitle(LangZh, "樱花之诗")
+	item.AddAlias("サクラノ詩")
+	if !reflect.DeepEqual(item.Aliases, []string{"樱花之诗"}) {
+		t.Errorf("unexpected aliases %v", item.Aliases)
+	}
+	if got := item.Title(LangEn, LangZh); got != "樱之诗" {
+		t.Errorf("Title(en, zh) = %q", got)
+	}
+	if got := item.Title(LangEn); got != "サクラノ詩" {
+		t.Errorf("Title(en) = %q", got)
+	}
+	if got := item.AllTitles(); !reflect.DeepEqual(got, []string{"サクラノ詩", "樱之诗", "樱花之诗"}) {
+		t.Errorf("AllTitles() = %v", got)
+	}
+
+	other := &Item{Titles: map[string]string{LangZh: "樱诗", LangEn: "Sakura no Uta"}}
+	merged := Merge(item, other)
+	if merged.Titles[LangZh] != "樱之诗" || merged.Titles[LangEn] != "Sakura no Uta" ||
+		!reflect.DeepEqual(merged.Aliases, []string{"樱花之诗", "樱诗"}) {
+		t.Errorf("unexpected merged titles %v aliases %v", merged.Titles, merged.Aliases)
+	}
+}
+
+func TestBangumi_GetItemTitles(t *testing.T) {
+	item := &Item{}
+	BangumiScraper.GetItemTitles(item, []byte(`{"name": "千恋＊万花", "name_cn": "千恋万花", "infobox": [
+		{"key": "中文名", "value": "千恋万花"},
+		{"key": "别名", "value": [{"v": "Senren Banka"}, {"k": "英文名", "v": "Senren＊Banka"}]}
+	]}`))
+	want := map[string]string{LangJa: "千恋＊万花", LangZh: "千恋万花", LangEn: "Senren＊Banka"}
+	if item.OriginalTitle != "千恋＊万花" || !reflect.DeepEqual(item.Titles, want) ||
+		!reflect.DeepEqual(item.Aliases, []string{"Senren Banka"}) {
+		t.Errorf("unexpected titles %q %v %v", item.OriginalTitle, item.Titles, item.Aliases)
+	}
+}
+
+func TestTwoDFan_GetItemAliases(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="media-body control-group">
+		<p class="tags">又名：樱之诗、Sakura no Uta</p>
+		<p class="tags">品牌：<a href="/brands/1">枕</a></p>
+	</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases, _ := (&TwoDFan{}).GetItemAliases(doc)
+	if !reflect.DeepEqual(aliases, []string{"樱之诗", "Sakura no Uta"}) {
+		t.Errorf("unexpected aliases %v", aliases)
+	}
+}
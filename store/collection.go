@@ -0,0 +1,79 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"scraper/scraper"
+	"time"
+)
+
+// Collection 本地库中条目的游玩状态
+type Collection struct {
+	ItemID    int64
+	Type      scraper.CollectionType
+	Rate      int
+	Comment   string
+	UpdatedAt time.Time
+	SyncedAt  time.Time // 上次同步到 bangumi 的时间，从未同步过时为零值
+	BangumiID string    // 条目在 bangumi 的编号，只在 UnsyncedCollections 中返回
+}
+
+// SetCollection 记录条目的游玩状态，UpdatedAt 为零值时使用当前时间
+func (s *Store) SetCollection(c Collection) error {
+	if c.UpdatedAt.IsZero() {
+		c.UpdatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`INSERT INTO collections (item_id, type, rate, comment, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (item_id) DO UPDATE SET
+			type = excluded.type,
+			rate = excluded.rate,
+			comment = excluded.comment,
+			updated_at = excluded.updated_at`,
+		c.ItemID, int(c.Type), c.Rate, c.Comment, c.UpdatedAt.UTC())
+	return err
+}
+
+// GetCollection 读取条目的游玩状态，没有记录时返回 ErrNotFound
+func (s *Store) GetCollection(itemID int64) (*Collection, error) {
+	c := &Collection{ItemID: itemID}
+	var synced sql.NullTime
+	err := s.db.QueryRow(`SELECT type, rate, comment, updated_at, synced_at FROM collections WHERE item_id = ?`, itemID).
+		Scan(&c.Type, &c.Rate, &c.Comment, &c.UpdatedAt, &synced)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	c.SyncedAt = synced.Time
+	return c, err
+}
+
+// UnsyncedCollections 返回上次同步后修改过、且知道 bangumi 编号的游玩状态
+func (s *Store) UnsyncedCollections() ([]Collection, error) {
+	// 条目自身的来源编号也登记在 external_ids 中
+	rows, err := s.db.Query(`
+		SELECT c.item_id, c.type, c.rate, c.comment, c.updated_at, c.synced_at, e.external_id
+		FROM collections c
+		JOIN external_ids e ON e.item_id = c.item_id AND e.source = 'bangumi'
+		WHERE c.synced_at IS NULL OR c.synced_at < c.updated_at
+		ORDER BY c.updated_at, c.item_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var collections []Collection
+	for rows.Next() {
+		var c Collection
+		var synced sql.NullTime
+		if err = rows.Scan(&c.ItemID, &c.Type, &c.Rate, &c.Comment, &c.UpdatedAt, &synced, &c.BangumiID); err != nil {
+			return nil, err
+		}
+		c.SyncedAt = synced.Time
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// MarkCollectionSynced 记录游玩状态已同步
+func (s *Store) MarkCollectionSynced(itemID int64, t time.Time) error {
+	_, err := s.db.Exec(`UPDATE collections SET synced_at = ? WHERE item_id = ?`, t.UTC(), itemID)
+	return err
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"scraper/scraper"
+	"time"
+)
+
+// Version 条目的一个历史版本
+type Version struct {
+	Version   int
+	Item      *scraper.Item
+	CreatedAt time.Time
+}
+
+// saveVersion 内容与最新版本不同时保存为新版本
+func saveVersion(tx *sql.Tx, id int64, data string) error {
+	var version int
+	var latest string
+	err := tx.QueryRow(`SELECT version, data FROM item_versions WHERE item_id = ? ORDER BY version DESC LIMIT 1`, id).
+		Scan(&version, &latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if latest == data {
+		return nil
+	}
+	_, err = tx.Exec(`INSERT INTO item_versions (item_id, version, data) VALUES (?, ?, ?)`, id, version+1, data)
+	return err
+}
+
+// History 返回条目的所有历史版本，按版本号升序，每个版本按 f 过滤剧透和色情内容
+func (s *Store) History(id int64, f scraper.Filter) ([]Version, error) {
+	rows, err := s.db.Query(`SELECT version, data, created_at FROM item_versions WHERE item_id = ? ORDER BY version`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []Version
+	for rows.Next() {
+		var v Version
+		var data string
+		if err = rows.Scan(&v.Version, &data, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.Item = &scraper.Item{}
+		if err = json.Unmarshal([]byte(data), v.Item); err != nil {
+			return nil, err
+		}
+		v.Item = v.Item.Filtered(f)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// DiffVersions 比较条目的两个版本，两个版本都先按 f 过滤剧透和色情内容
+func (s *Store) DiffVersions(id int64, from, to int, f scraper.Filter) (scraper.Changes, error) {
+	old, err := s.version(id, from, f)
+	if err != nil {
+		return nil, err
+	}
+	new, err := s.version(id, to, f)
+	if err != nil {
+		return nil, err
+	}
+	return scraper.Diff(old, new), nil
+}
+
+// LatestChanges 返回最新版本相对上一个版本的变化，只有一个版本时返回 nil，按 f 过滤剧透和色情内容
+func (s *Store) LatestChanges(id int64, f scraper.Filter) (scraper.Changes, error) {
+	var latest int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM item_versions WHERE item_id = ?`, id).Scan(&latest)
+	if err != nil {
+		return nil, err
+	}
+	if latest == 0 {
+		return nil, ErrNotFound
+	}
+	if latest == 1 {
+		return nil, nil
+	}
+	return s.DiffVersions(id, latest-1, latest, f)
+}
+
+func (s *Store) version(id int64, version int, f scraper.Filter) (*scraper.Item, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM item_versions WHERE item_id = ? AND version = ?`, id, version).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("版本 %d: %w", version, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	item := &scraper.Item{}
+	if err = json.Unmarshal([]byte(data), item); err != nil {
+		return nil, err
+	}
+	return item.Filtered(f), nil
+}
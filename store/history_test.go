@@ -0,0 +1,65 @@
+package store
+
+import (
+	"fmt"
+	"scraper/scraper"
+	"strings"
+	"testing"
+)
+
+func TestStore_History(t *testing.T) {
+	s := openTestStore(t)
+	item := testItems()[1]
+	id, err := s.Upsert(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内容没有变化时不产生新版本
+	if _, err = s.Upsert(item); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := s.LatestChanges(id, scraper.ShowAll); err != nil || changes != nil {
+		t.Errorf("expected no changes, got %v, %v", changes, err)
+	}
+
+	item.ReleaseDate = "2023年3月31日"
+	item.Preview = append(item.Preview, "new")
+	if _, err = s.Upsert(item); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := s.History(id, scraper.ShowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Item.ReleaseDate != "2023年2月24日" || versions[1].CreatedAt.IsZero() {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+
+	changes, err := s.LatestChanges(id, scraper.ShowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes.Postponed(); !ok {
+		t.Errorf("expected postponement in %v", changes)
+	}
+	if len(changes.Field("Preview")) != 1 || changes.Field("Preview")[0].Kind != scraper.Added {
+		t.Errorf("unexpected preview changes %v", changes)
+	}
+	if _, err = s.DiffVersions(id, 1, 5, scraper.ShowAll); err == nil {
+		t.Error("expected error for missing version")
+	}
+
+	// 默认隐藏剧透，历史版本和变化中都不会出现剧透标签和介绍
+	item.Tags[0].Item = append(item.Tags[0].Item, scraper.TagItem{Identity: "twist", Name: "叙述诡计", Spoiler: scraper.SpoilerMajor})
+	item.Character = []scraper.Character{{Name: "雫", Introduction: "全部都是剧透", Spoiler: scraper.SpoilerMajor}}
+	if _, err = s.Upsert(item); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err = s.LatestChanges(id, scraper.Filter{}); err != nil || strings.Contains(fmt.Sprint(changes), "twist") || strings.Contains(fmt.Sprint(changes), "剧透") {
+		t.Errorf("spoilers should be hidden from changes, got %v, %v", changes, err)
+	}
+	if versions, err = s.History(id, scraper.Filter{}); err != nil || len(versions[2].Item.Tags[0].Item) != 1 || versions[2].Item.Character[0].Introduction != "" {
+		t.Errorf("spoilers should be hidden from history, got %+v, %v", versions, err)
+	}
+}
@@ -0,0 +1,197 @@
+package store
+
+import "fmt"
+
+// migrations 按顺序执行的建表语句，只能追加不能修改
+var migrations = []string{
+	`CREATE TABLE items (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		source       TEXT NOT NULL,
+		source_id    TEXT NOT NULL,
+		origin       TEXT NOT NULL,
+		name         TEXT NOT NULL DEFAULT '',
+		brand        TEXT NOT NULL DEFAULT '',
+		release_date TEXT NOT NULL DEFAULT '',
+		size_bytes   INTEGER NOT NULL DEFAULT 0,
+		magnet       TEXT NOT NULL DEFAULT '',
+		data         TEXT NOT NULL,
+		created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (source, source_id)
+	);
+	CREATE INDEX idx_items_name ON items (name);
+	CREATE INDEX idx_items_brand ON items (brand);
+	CREATE INDEX idx_items_release_date ON items (release_date);
+
+	CREATE TABLE tags (
+		item_id           INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		category_identity TEXT NOT NULL DEFAULT '',
+		category          TEXT NOT NULL DEFAULT '',
+		identity          TEXT NOT NULL,
+		name              TEXT NOT NULL
+	);
+	CREATE INDEX idx_tags_item ON tags (item_id);
+	CREATE INDEX idx_tags_name ON tags (name);
+	CREATE INDEX idx_tags_identity ON tags (identity);
+
+	CREATE TABLE characters (
+		item_id      INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		idx          INTEGER NOT NULL,
+		name         TEXT NOT NULL,
+		introduction TEXT NOT NULL DEFAULT '',
+		avatar       TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_characters_item ON characters (item_id);
+	CREATE INDEX idx_characters_name ON characters (name);
+
+	CREATE TABLE images (
+		item_id INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		kind    TEXT NOT NULL,
+		idx     INTEGER NOT NULL,
+		url     TEXT NOT NULL
+	);
+	CREATE INDEX idx_images_item ON images (item_id);
+
+	CREATE TABLE external_ids (
+		item_id     INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		source      TEXT NOT NULL,
+		external_id TEXT NOT NULL,
+		PRIMARY KEY (item_id, source)
+	);
+	CREATE INDEX idx_external_ids_source ON external_ids (source, external_id);`,
+
+	`CREATE TABLE item_versions (
+		item_id    INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		version    INTEGER NOT NULL,
+		data       TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (item_id, version)
+	);
+	INSERT INTO item_versions (item_id, version, data, created_at) SELECT id, 1, data, updated_at FROM items;`,
+
+	`CREATE TABLE batch_tasks (
+		batch       TEXT NOT NULL,
+		url         TEXT NOT NULL,
+		status      TEXT NOT NULL DEFAULT 'pending',
+		attempts    INTEGER NOT NULL DEFAULT 0,
+		error       TEXT NOT NULL DEFAULT '',
+		retry_after DATETIME,
+		item_id     INTEGER REFERENCES items (id) ON DELETE SET NULL,
+		created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (batch, url)
+	);
+	CREATE INDEX idx_batch_tasks_status ON batch_tasks (batch, status);`,
+
+	`CREATE TABLE jobs (
+		name     TEXT PRIMARY KEY,
+		last_run DATETIME NOT NULL
+	);`,
+
+	`CREATE TABLE staff (
+		item_id INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		role    TEXT NOT NULL,
+		name    TEXT NOT NULL
+	);
+	CREATE INDEX idx_staff_item ON staff (item_id);
+	CREATE INDEX idx_staff_name ON staff (name, role);` + backfillStaff,
+
+	`CREATE TABLE collections (
+		item_id    INTEGER PRIMARY KEY REFERENCES items (id) ON DELETE CASCADE,
+		type       INTEGER NOT NULL,
+		rate       INTEGER NOT NULL DEFAULT 0,
+		comment    TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME NOT NULL,
+		synced_at  DATETIME
+	);`,
+
+	`CREATE TABLE titles (
+		item_id INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+		lang    TEXT NOT NULL DEFAULT '',
+		title   TEXT NOT NULL
+	);
+	CREATE INDEX idx_titles_item ON titles (item_id);
+	CREATE INDEX idx_titles_title ON titles (title);` + backfillTitles,
+
+	// 按标签查询时需要排除被过滤的标签，已有条目的标签从 data 中补全
+	`ALTER TABLE tags ADD COLUMN spoiler INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tags ADD COLUMN sexual INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tags ADD COLUMN count INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tags ADD COLUMN confidence REAL NOT NULL DEFAULT 0;
+	UPDATE tags SET
+		spoiler = COALESCE(m.spoiler, 0),
+		sexual = COALESCE(m.sexual, 0),
+		count = COALESCE(m.count, 0),
+		confidence = COALESCE(m.confidence, 0)
+	FROM (
+		SELECT i.id AS item_id,
+			json_extract(tg.value, '$.Category.Identity') AS category_identity,
+			json_extract(ti.value, '$.Identity') AS identity,
+			json_extract(ti.value, '$.Spoiler') AS spoiler,
+			json_extract(ti.value, '$.Sexual') AS sexual,
+			json_extract(ti.value, '$.Count') AS count,
+			json_extract(ti.value, '$.Confidence') AS confidence
+		FROM items i, json_each(i.data, '$.Tags') tg, json_each(tg.value, '$.Item') ti
+	) m
+	WHERE m.item_id = tags.item_id AND m.category_identity = tags.category_identity AND m.identity = tags.identity;`,
+}
+
+// backfillStaff 从已有条目的 data 中补全制作人员，声优按 、 拆分，与 Upsert 一致
+const backfillStaff = `
+	INSERT INTO staff (item_id, role, name)
+	SELECT i.id, json_extract(st.value, '$.Role'), json_extract(st.value, '$.Name')
+	FROM items i, json_each(i.data, '$.Staff') st WHERE st.type = 'object';
+	WITH RECURSIVE actors (item_id, name, rest) AS (
+		SELECT i.id, '', json_extract(c.value, '$.VoiceActor') || '、'
+		FROM items i, json_each(i.data, '$.Character') c WHERE c.type = 'object'
+		UNION ALL
+		SELECT item_id, trim(substr(rest, 1, instr(rest, '、') - 1), ' 　'), substr(rest, instr(rest, '、') + 1)
+		FROM actors WHERE rest != ''
+	)
+	INSERT INTO staff (item_id, role, name) SELECT item_id, '声优', name FROM actors WHERE name != '';`
+
+// backfillTitles 从已有条目的 data 中补全原名、名称、各语言名称和别名，与 Item.AllTitles 一致
+const backfillTitles = `
+	INSERT INTO titles (item_id, lang, title)
+	SELECT item_id, MAX(lang), title FROM (
+		SELECT id AS item_id, '' AS lang, json_extract(data, '$.OriginalTitle') AS title FROM items
+		UNION ALL
+		SELECT id, '', json_extract(data, '$.Name') FROM items
+		UNION ALL
+		SELECT i.id, t.key, t.value FROM items i, json_each(i.data, '$.Titles') t WHERE t.type = 'text'
+		UNION ALL
+		SELECT i.id, '', a.value FROM items i, json_each(i.data, '$.Aliases') a WHERE a.type = 'text'
+	)
+	WHERE title IS NOT NULL AND title != ''
+	GROUP BY item_id, title;`
+
+// Migrate 执行尚未执行过的迁移
+func (s *Store) Migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+	var version int
+	err = s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("执行迁移 %d 失败: %w", i+1, err)
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
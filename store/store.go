@@ -0,0 +1,304 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	_ "modernc.org/sqlite"
+	"scraper/scraper"
+	"scraper/tools"
+	"strings"
+)
+
+var ErrNotFound = errors.New("条目不存在")
+
+// Store 基于 SQLite 的本地条目库
+type Store struct {
+	db *sql.DB
+}
+
+// Open 打开数据库文件并执行迁移
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同一时间只允许一个写入者
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db}
+	if err = s.Migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB 返回底层数据库连接，供其它工具直接查询
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// SourceID 由 Item.Origin 得到数据源和编号，无法识别时以 Origin 作为编号
+func SourceID(item *scraper.Item) (string, string) {
+	if code, ok := tools.ParseCode(item.Origin); ok {
+		return code.Source, code.ID
+	}
+	return "", item.Origin
+}
+
+// Upsert 按数据源编号插入或更新条目，返回条目的 id
+func (s *Store) Upsert(item *scraper.Item) (int64, error) {
+	source, sourceID := SourceID(item)
+	if sourceID == "" {
+		return 0, errors.New("条目缺少来源")
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO items (source, source_id, origin, name, brand, release_date, size_bytes, magnet, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, source_id) DO UPDATE SET
+			origin = excluded.origin,
+			name = excluded.name,
+			brand = excluded.brand,
+			release_date = excluded.release_date,
+			size_bytes = excluded.size_bytes,
+			magnet = excluded.magnet,
+			data = excluded.data,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id`,
+		source, sourceID, item.Origin, item.Name, item.Brand, tools.NormalizeDate(item.ReleaseDate),
+		item.SizeBytes, item.Magnet, string(data),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = saveVersion(tx, id, string(data)); err != nil {
+		return 0, err
+	}
+
+	for _, table := range []string{"tags", "characters", "images", "external_ids", "staff", "titles"} {
+		if _, err = tx.Exec(`DELETE FROM `+table+` WHERE item_id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	for _, tag := range item.Tags {
+		for _, t := range tag.Item {
+			_, err = tx.Exec(`INSERT INTO tags (item_id, category_identity, category, identity, name, spoiler, sexual, count, confidence)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, tag.Category.Identity, tag.Category.Name, t.Identity, t.Name, t.Spoiler, t.Sexual, t.Count, t.Confidence)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	for i, c := range item.Character {
+		_, err = tx.Exec(`INSERT INTO characters (item_id, idx, name, introduction, avatar) VALUES (?, ?, ?, ?, ?)`,
+			id, i, c.Name, c.Introduction, c.Avatar)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// 声优也作为制作人员登记，方便按姓名查询
+	staff := append([]scraper.Staff(nil), item.Staff...)
+	for _, c := range item.Character {
+		for _, name := range strings.Split(c.VoiceActor, "、") {
+			if name = strings.TrimSpace(name); name != "" {
+				staff = append(staff, scraper.Staff{Role: "声优", Name: name})
+			}
+		}
+	}
+	for _, s := range staff {
+		if _, err = tx.Exec(`INSERT INTO staff (item_id, role, name) VALUES (?, ?, ?)`, id, s.Role, s.Name); err != nil {
+			return 0, err
+		}
+	}
+	// 原名、各语言名称和别名都登记，按名称查询时可以匹配任意一个
+	langs := make(map[string]string, len(item.Titles))
+	for lang, title := range item.Titles {
+		langs[title] = lang
+	}
+	for _, title := range item.AllTitles() {
+		if _, err = tx.Exec(`INSERT INTO titles (item_id, lang, title) VALUES (?, ?, ?)`, id, langs[title], title); err != nil {
+			return 0, err
+		}
+	}
+	images := map[string][]string{"preview": item.Preview}
+	if item.Cover != "" {
+		images["cover"] = []string{item.Cover}
+	}
+	for kind, urls := range images {
+		for i, u := range urls {
+			if _, err = tx.Exec(`INSERT INTO images (item_id, kind, idx, url) VALUES (?, ?, ?, ?)`, id, kind, i, u); err != nil {
+				return 0, err
+			}
+		}
+	}
+	ids := map[string]string{}
+	for k, v := range item.ExternalIDs {
+		ids[k] = v
+	}
+	if source != "" {
+		ids[source] = sourceID
+	}
+	for k, v := range ids {
+		if _, err = tx.Exec(`INSERT INTO external_ids (item_id, source, external_id) VALUES (?, ?, ?)`, id, k, v); err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	return id, err
+}
+
+// Get 按 id 读取条目，按 f 过滤剧透和色情内容，需要完整数据时使用 scraper.ShowAll
+func (s *Store) Get(id int64, f scraper.Filter) (*scraper.Item, error) {
+	return s.scanOne(f, s.db.QueryRow(`SELECT data FROM items WHERE id = ?`, id))
+}
+
+// GetBySource 按数据源编号读取条目，也会匹配条目上记录的外部编号，按 f 过滤剧透和色情内容
+func (s *Store) GetBySource(source, id string, f scraper.Filter) (*scraper.Item, error) {
+	return s.scanOne(f, s.db.QueryRow(`
+		SELECT data FROM items WHERE source = ? AND source_id = ?
+		UNION ALL
+		SELECT i.data FROM items i JOIN external_ids e ON e.item_id = i.id WHERE e.source = ? AND e.external_id = ?
+		LIMIT 1`, source, id, source, id))
+}
+
+// ItemID 返回条目在库中的 id，不存在时返回 ErrNotFound
+func (s *Store) ItemID(item *scraper.Item) (int64, error) {
+	source, sourceID := SourceID(item)
+	var id int64
+	err := s.db.QueryRow(`SELECT id FROM items WHERE source = ? AND source_id = ?`, source, sourceID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// Delete 删除条目及其关联数据
+func (s *Store) Delete(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM items WHERE id = ?`, id)
+	return err
+}
+
+// Query 查询条件，空字段不参与过滤
+type Query struct {
+	Name   string         // 名称、原名或别名包含
+	Brand  string         // 品牌包含
+	Tag    string         // 标签名称或标识
+	Staff  string         // 制作人员或声优姓名
+	Role   string         // 制作人员职务，与 Staff 一起使用，例如 剧本、原画
+	From   string         // 发售日起始，含
+	To     string         // 发售日截止，含
+	Filter scraper.Filter // 过滤剧透和色情内容，零值隐藏剧透，使用 scraper.ShowAll 不过滤
+	Limit  int
+	Offset int
+}
+
+// Find 按条件查询条目，按发售日倒序返回
+func (s *Store) Find(q Query) ([]*scraper.Item, error) {
+	var where []string
+	var args []interface{}
+	if q.Name != "" {
+		where = append(where, `(i.name LIKE ? OR EXISTS (SELECT 1 FROM titles ti WHERE ti.item_id = i.id AND ti.title LIKE ?))`)
+		args = append(args, "%"+q.Name+"%", "%"+q.Name+"%")
+	}
+	if q.Brand != "" {
+		where = append(where, `i.brand LIKE ?`)
+		args = append(args, "%"+q.Brand+"%")
+	}
+	if q.Tag != "" {
+		// 被过滤的标签不参与查询，与 scraper.Filter.Allow 一致
+		cond := `(t.name = ? OR t.identity = ?) AND t.spoiler <= ? AND (t.count = 0 OR t.confidence >= ?)`
+		args = append(args, q.Tag, q.Tag, q.Filter.MaxSpoiler, q.Filter.MinConfidence)
+		if q.Filter.HideSexual {
+			cond += ` AND t.sexual = 0`
+		}
+		where = append(where, `EXISTS (SELECT 1 FROM tags t WHERE t.item_id = i.id AND `+cond+`)`)
+	}
+	if q.Staff != "" {
+		cond := `s.name = ?`
+		args = append(args, q.Staff)
+		if q.Role != "" {
+			cond += ` AND s.role = ?`
+			args = append(args, scraper.NormalizeRole(q.Role))
+		}
+		where = append(where, `EXISTS (SELECT 1 FROM staff s WHERE s.item_id = i.id AND `+cond+`)`)
+	}
+	if from := tools.NormalizeDate(q.From); from != "" {
+		where = append(where, `i.release_date >= ?`)
+		args = append(args, from)
+	}
+	if to := tools.NormalizeDate(q.To); to != "" {
+		where = append(where, `i.release_date != '' AND i.release_date <= ?`)
+		// 只有年月时包含整月
+		if len(to) == len("2006-01") {
+			to += "-31"
+		}
+		args = append(args, to)
+	}
+
+	query := `SELECT i.data FROM items i`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY i.release_date DESC, i.id`
+	if q.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, q.Limit, q.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*scraper.Item
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		item := &scraper.Item{}
+		if err = json.Unmarshal([]byte(data), item); err != nil {
+			return nil, err
+		}
+		items = append(items, item.Filtered(q.Filter))
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) scanOne(f scraper.Filter, row *sql.Row) (*scraper.Item, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	item := &scraper.Item{}
+	if err := json.Unmarshal([]byte(data), item); err != nil {
+		return nil, err
+	}
+	return item.Filtered(f), nil
+}
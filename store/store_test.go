@@ -0,0 +1,270 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"scraper/scraper"
+	"strings"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	s, err := Open(filepath.Join(t.TempDir(), "items.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func testItems() []*scraper.Item {
+	return []*scraper.Item{
+		{
+			Name:        "サクラノ詩",
+			Brand:       "枕",
+			ReleaseDate: "2015/10/23",
+			Origin:      "https://api.bgm.tv/v0/subjects/123",
+			Preview:     []string{"p1", "p2"},
+			Tags:        []scraper.Tag{{Item: []scraper.TagItem{{Identity: "adv", Name: "ADV"}}}},
+			Character:   []scraper.Character{{Name: "夏目 藍", VoiceActor: "遠野そよぎ"}},
+			Staff:       []scraper.Staff{{Role: "剧本", Name: "すかぢ"}, {Role: "原画", Name: "基4%"}},
+			ExternalIDs: map[string]string{"getchu": "111"},
+			Titles:      map[string]string{"ja": "サクラノ詩", "zh": "樱之诗"},
+			Aliases:     []string{"Sakura no Uta"},
+		},
+		{
+			Name:        "サクラノ刻",
+			Brand:       "枕",
+			ReleaseDate: "2023年2月24日",
+			Origin:      "https://www.getchu.com/soft.phtml?id=222",
+			Tags:        []scraper.Tag{{Item: []scraper.TagItem{{Identity: "nakige", Name: "泣き"}}}},
+			Staff:       []scraper.Staff{{Role: "剧本", Name: "すかぢ"}},
+		},
+		{
+			Name:        "Other",
+			Brand:       "Brand",
+			ReleaseDate: "2020-01-01",
+			Origin:      "https://2dfan.org/subjects/4566",
+		},
+	}
+}
+
+func TestStore_Upsert(t *testing.T) {
+	s := openTestStore(t)
+	items := testItems()
+	id, err := s.Upsert(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items[0].Name = "サクラノ詩 -櫻の森の上を舞う-"
+	id2, err := s.Upsert(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != id2 {
+		t.Errorf("upsert should update the same row: %d != %d", id, id2)
+	}
+
+	item, err := s.Get(id, scraper.ShowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != items[0].Name || len(item.Preview) != 2 || len(item.Character) != 1 {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if item, err = s.GetBySource("getchu", "111", scraper.ShowAll); err != nil || item.Brand != "枕" {
+		t.Errorf("GetBySource by external id = %+v, %v", item, err)
+	}
+	if _, err = s.GetBySource("getchu", "999", scraper.ShowAll); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var n int
+	if err = s.DB().QueryRow(`SELECT COUNT(*) FROM tags WHERE item_id = ?`, id).Scan(&n); err != nil || n != 1 {
+		t.Errorf("tags should be replaced on upsert, got %d, %v", n, err)
+	}
+	if err = s.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.DB().QueryRow(`SELECT COUNT(*) FROM images WHERE item_id = ?`, id).Scan(&n); err != nil || n != 0 {
+		t.Errorf("images should be deleted with item, got %d, %v", n, err)
+	}
+}
+
+func TestStore_Find(t *testing.T) {
+	s := openTestStore(t)
+	for _, item := range testItems() {
+		if _, err := s.Upsert(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		q    Query
+		want []string
+	}{
+		{Query{Brand: "枕"}, []string{"サクラノ刻", "サクラノ詩"}},
+		{Query{Name: "サクラ", Tag: "泣き"}, []string{"サクラノ刻"}},
+		{Query{Tag: "adv"}, []string{"サクラノ詩"}},
+		{Query{From: "2016-01-01", To: "2023/02"}, []string{"サクラノ刻", "Other"}},
+		{Query{Limit: 1, Offset: 1}, []string{"Other"}},
+		{Query{Staff: "すかぢ", Role: "シナリオ"}, []string{"サクラノ刻", "サクラノ詩"}},
+		{Query{Staff: "基4%", Role: "剧本"}, nil},
+		{Query{Staff: "遠野そよぎ"}, []string{"サクラノ詩"}},
+		{Query{Name: "樱之"}, []string{"サクラノ詩"}},
+		{Query{Name: "no Uta"}, []string{"サクラノ詩"}},
+	}
+	for _, c := range cases {
+		items, err := s.Find(c.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		if len(names) != len(c.want) {
+			t.Errorf("Find(%+v) = %v, want %v", c.q, names, c.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != c.want[i] {
+				t.Errorf("Find(%+v) = %v, want %v", c.q, names, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestStore_FindFilter(t *testing.T) {
+	s := openTestStore(t)
+	item := testItems()[1]
+	item.Tags[0].Item = append(item.Tags[0].Item, scraper.TagItem{Identity: "twist", Name: "叙述诡计", Spoiler: scraper.SpoilerMajor})
+	id, err := s.Upsert(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 默认隐藏剧透
+	items, err := s.Find(Query{})
+	if err != nil || len(items) != 1 || len(items[0].Tags[0].Item) != 1 {
+		t.Errorf("spoiler tags should be filtered, got %+v, %v", items, err)
+	}
+	if items, _ = s.Find(Query{Filter: scraper.ShowAll}); len(items) != 1 || items[0].Tags[0].Item[1].Spoiler != scraper.SpoilerMajor {
+		t.Errorf("ShowAll should keep spoiler tags, got %+v", items)
+	}
+	if got, err := s.Get(id, scraper.Filter{}); err != nil || len(got.Tags[0].Item) != 1 {
+		t.Errorf("Get should filter spoiler tags, got %+v, %v", got, err)
+	}
+	// 被隐藏的标签不能用于查询
+	if items, _ = s.Find(Query{Tag: "twist"}); len(items) != 0 {
+		t.Errorf("hidden spoiler tag should not match, got %+v", items)
+	}
+	if items, _ = s.Find(Query{Tag: "twist", Filter: scraper.ShowAll}); len(items) != 1 {
+		t.Errorf("ShowAll should match spoiler tags, got %+v", items)
+	}
+}
+
+func TestStore_Migrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+	// 重复打开不会重复执行迁移
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err = s.Upsert(&scraper.Item{Name: "a", Origin: "https://2dfan.org/subjects/1", Tags: []scraper.Tag{{Item: []scraper.TagItem{
+		{Identity: "twist", Name: "叙述诡计", Spoiler: scraper.SpoilerMajor, Sexual: true, Count: 3, Confidence: 0.5},
+	}}}}); err != nil {
+		t.Fatal(err)
+	}
+	var version int
+	if err = s.DB().QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || version != len(migrations) {
+		t.Errorf("version = %d, %v", version, err)
+	}
+
+	// 旧版本的标签表没有剧透和色情标记，迁移时从 data 中补全
+	for _, column := range []string{"spoiler", "sexual", "count", "confidence"} {
+		if _, err = s.DB().Exec(`ALTER TABLE tags DROP COLUMN ` + column); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = s.DB().Exec(`DELETE FROM schema_migrations WHERE version = ?`, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	var spoiler, count int
+	var sexual bool
+	var confidence float64
+	err = s.DB().QueryRow(`SELECT spoiler, sexual, count, confidence FROM tags WHERE identity = 'twist'`).Scan(&spoiler, &sexual, &count, &confidence)
+	if err != nil || spoiler != 2 || !sexual || count != 3 || confidence != 0.5 {
+		t.Errorf("tag flags not migrated: %d %v %d %v, %v", spoiler, sexual, count, confidence, err)
+	}
+}
+
+// backfillRows 清空表后执行补全，返回补全前后的所有行
+func backfillRows(t *testing.T, s *Store, table, columns, backfill string) (before, after []string) {
+	query := `SELECT ` + columns + ` FROM ` + table + ` ORDER BY ` + columns
+	read := func() []string {
+		rows, err := s.DB().Query(`SELECT ` + strings.ReplaceAll(columns, ", ", ` || '|' || `) + ` FROM (` + query + `)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var row string
+			if err = rows.Scan(&row); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, row)
+		}
+		return got
+	}
+	before = read()
+	if _, err := s.DB().Exec(`DELETE FROM ` + table); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DB().Exec(backfill); err != nil {
+		t.Fatal(err)
+	}
+	return before, read()
+}
+
+func TestStore_BackfillStaff(t *testing.T) {
+	s := openTestStore(t)
+	for _, item := range testItems() {
+		item.Character = append(item.Character, scraper.Character{Name: "鳥谷 真琴", VoiceActor: "涼屋スイ、 遥そら"})
+		if _, err := s.Upsert(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 迁移前已有的条目补全后与 Upsert 写入的结果相同
+	before, after := backfillRows(t, s, "staff", "item_id, role, name", backfillStaff)
+	if len(before) == 0 || !reflect.DeepEqual(before, after) {
+		t.Errorf("backfilled staff = %v, want %v", after, before)
+	}
+}
+
+func TestStore_BackfillTitles(t *testing.T) {
+	s := openTestStore(t)
+	for _, item := range testItems() {
+		item.OriginalTitle = item.Name
+		if _, err := s.Upsert(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, after := backfillRows(t, s, "titles", "item_id, lang, title", backfillTitles)
+	if len(before) == 0 || !reflect.DeepEqual(before, after) {
+		t.Errorf("backfilled titles = %v, want %v", after, before)
+	}
+	if items, _ := s.Find(Query{Name: "no Uta"}); len(items) != 1 {
+		t.Errorf("aliases should be searchable after backfill, got %+v", items)
+	}
+}
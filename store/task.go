@@ -0,0 +1,190 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type TaskStatus string
+
+const (
+	TaskPending TaskStatus = "pending" // 等待抓取
+	TaskDone    TaskStatus = "done"    // 已完成
+	TaskRetry   TaskStatus = "retry"   // 失败，等待重试
+	TaskFailed  TaskStatus = "failed"  // 失败且不再重试
+)
+
+// Task 批量抓取中单个链接的状态
+type Task struct {
+	Batch      string
+	Url        string
+	Status     TaskStatus
+	Attempts   int
+	Error      string
+	RetryAfter time.Time // 状态为 retry 时，在此之前不会再次抓取
+	ItemID     int64     // 抓取成功后对应的条目 id
+}
+
+// AddTasks 将链接加入批次，已存在的链接保持原状态
+func (s *Store) AddTasks(batch string, urls []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = addTasks(tx, batch, urls); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func addTasks(tx *sql.Tx, batch string, urls []string) error {
+	stmt, err := tx.Prepare(`INSERT INTO batch_tasks (batch, url) VALUES (?, ?) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, u := range urls {
+		if _, err = stmt.Exec(batch, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadyTasks 返回批次中可以抓取的任务：未开始的以及已到重试时间的
+func (s *Store) ReadyTasks(batch string, now time.Time) ([]Task, error) {
+	return s.queryTasks(`SELECT batch, url, status, attempts, error, retry_after, item_id FROM batch_tasks
+		WHERE batch = ? AND (status = ? OR (status = ? AND (retry_after IS NULL OR retry_after <= ?)))
+		ORDER BY created_at, url`, batch, TaskPending, TaskRetry, now.UTC())
+}
+
+// Tasks 返回批次中的所有任务
+func (s *Store) Tasks(batch string) ([]Task, error) {
+	return s.queryTasks(`SELECT batch, url, status, attempts, error, retry_after, item_id FROM batch_tasks
+		WHERE batch = ? ORDER BY created_at, url`, batch)
+}
+
+// TaskCounts 按状态统计批次中的任务数量
+func (s *Store) TaskCounts(batch string) (map[TaskStatus]int, error) {
+	rows, err := s.db.Query(`SELECT status, COUNT(*) FROM batch_tasks WHERE batch = ? GROUP BY status`, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[TaskStatus]int)
+	for rows.Next() {
+		var status TaskStatus
+		var n int
+		if err = rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// UpdateTask 保存任务状态
+func (s *Store) UpdateTask(task Task) error {
+	var retryAfter, itemID interface{}
+	if !task.RetryAfter.IsZero() {
+		retryAfter = task.RetryAfter.UTC()
+	}
+	if task.ItemID > 0 {
+		itemID = task.ItemID
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO batch_tasks (batch, url, status, attempts, error, retry_after, item_id) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (batch, url) DO UPDATE SET
+			status = excluded.status,
+			attempts = excluded.attempts,
+			error = excluded.error,
+			retry_after = excluded.retry_after,
+			item_id = excluded.item_id,
+			updated_at = CURRENT_TIMESTAMP`,
+		task.Batch, task.Url, task.Status, task.Attempts, task.Error, retryAfter, itemID)
+	return err
+}
+
+// ReplaceTasks 开始新一轮抓取，用于定时任务
+//
+// 已完成和已失败的任务被 urls 替换，待抓取和等待重试的任务保留原状态，不会提前重试。
+func (s *Store) ReplaceTasks(batch string, urls []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM batch_tasks WHERE batch = ? AND status IN (?, ?)`, batch, TaskDone, TaskFailed); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = addTasks(tx, batch, urls); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// NextRetry 返回批次中最早的重试时间，没有等待重试的任务时返回零值
+func (s *Store) NextRetry(batch string) (time.Time, error) {
+	var t sql.NullTime
+	err := s.db.QueryRow(`SELECT retry_after FROM batch_tasks WHERE batch = ? AND status = ? AND retry_after IS NOT NULL
+		ORDER BY retry_after LIMIT 1`, batch, TaskRetry).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t.Time, err
+}
+
+// ResetTasks 将批次中的任务重新置为待抓取，status 为空时重置所有任务
+func (s *Store) ResetTasks(batch string, status TaskStatus) error {
+	query := `UPDATE batch_tasks SET status = ?, attempts = 0, error = '', retry_after = NULL, updated_at = CURRENT_TIMESTAMP WHERE batch = ?`
+	args := []interface{}{TaskPending, batch}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	_, err := s.db.Exec(query, args...)
+	return err
+}
+
+func (s *Store) queryTasks(query string, args ...interface{}) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		var retryAfter sql.NullTime
+		var itemID sql.NullInt64
+		if err = rows.Scan(&t.Batch, &t.Url, &t.Status, &t.Attempts, &t.Error, &retryAfter, &itemID); err != nil {
+			return nil, err
+		}
+		if retryAfter.Valid {
+			t.RetryAfter = retryAfter.Time
+		}
+		t.ItemID = itemID.Int64
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// JobLastRun 返回定时任务上一次运行的时间，从未运行过时返回零值
+func (s *Store) JobLastRun(name string) (time.Time, error) {
+	var t sql.NullTime
+	err := s.db.QueryRow(`SELECT last_run FROM jobs WHERE name = ?`, name).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t.Time, err
+}
+
+// SetJobLastRun 记录定时任务的运行时间
+func (s *Store) SetJobLastRun(name string, t time.Time) error {
+	_, err := s.db.Exec(`INSERT INTO jobs (name, last_run) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET last_run = excluded.last_run`, name, t.UTC())
+	return err
+}
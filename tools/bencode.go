@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// BDecode 解码 bencode 数据
+//
+// 整数解码为 int64，字符串解码为 string，列表解码为 []interface{}，字典解码为 map[string]interface{}。
+func BDecode(data []byte) (interface{}, error) {
+	d := &bdecoder{data: data}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("bencode: 位置 %d 之后存在多余数据", d.pos)
+	}
+	return v, nil
+}
+
+type bdecoder struct {
+	data []byte
+	pos  int
+	// 顶层字典中各个键对应值的原始字节，用于计算 infohash
+	raw map[string][]byte
+}
+
+func (d *bdecoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, errors.New("bencode: 数据意外结束")
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		d.pos++
+		end := d.indexFrom('e')
+		if end < 0 {
+			return nil, errors.New("bencode: 整数未结束")
+		}
+		n, err := strconv.ParseInt(string(d.data[d.pos:end]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bencode: 无法解析整数: %v", err)
+		}
+		d.pos = end + 1
+		return n, nil
+	case c == 'l':
+		d.pos++
+		list := []interface{}{}
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		if d.pos >= len(d.data) {
+			return nil, errors.New("bencode: 列表未结束")
+		}
+		d.pos++
+		return list, nil
+	case c == 'd':
+		top := d.raw == nil
+		if top {
+			d.raw = make(map[string][]byte)
+		}
+		d.pos++
+		dict := make(map[string]interface{})
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			k, err := d.decodeString()
+			if err != nil {
+				return nil, err
+			}
+			start := d.pos
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			if top {
+				d.raw[k] = d.data[start:d.pos]
+			}
+			dict[k] = v
+		}
+		if d.pos >= len(d.data) {
+			return nil, errors.New("bencode: 字典未结束")
+		}
+		d.pos++
+		return dict, nil
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("bencode: 位置 %d 出现非法字符 %q", d.pos, c)
+	}
+}
+
+func (d *bdecoder) decodeString() (string, error) {
+	colon := d.indexFrom(':')
+	if colon < 0 {
+		return "", errors.New("bencode: 字符串长度未结束")
+	}
+	n, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("bencode: 无法解析字符串长度 %q", d.data[d.pos:colon])
+	}
+	start := colon + 1
+	if start+n > len(d.data) {
+		return "", errors.New("bencode: 字符串超出数据长度")
+	}
+	d.pos = start + n
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *bdecoder) indexFrom(c byte) int {
+	for i := d.pos; i < len(d.data); i++ {
+		if d.data[i] == c {
+			return i
+		}
+	}
+	return -1
+}
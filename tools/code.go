@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 func Jp2Utf8(originBytes []byte) string {
@@ -15,3 +18,161 @@ func Jp2Utf8(originBytes []byte) string {
 	}
 	return string(utf8Bytes)
 }
+
+// Code 商品编号
+type Code struct {
+	Source string // 来源，与 scraper 中注册的名称一致
+	ID     string // 规范化后的编号
+}
+
+func (c Code) String() string {
+	return c.Source + ":" + c.ID
+}
+
+// URL 返回编号对应的详情页地址，可直接交给对应的 scraper 抓取
+func (c Code) URL() string {
+	switch c.Source {
+	case "dlsite":
+		floor := "maniax"
+		switch {
+		case strings.HasPrefix(c.ID, "VJ"):
+			floor = "pro"
+		case strings.HasPrefix(c.ID, "BJ"):
+			floor = "books"
+		}
+		return fmt.Sprintf("https://www.dlsite.com/%s/work/=/product_id/%s.html", floor, c.ID)
+	case "fanza":
+		return fmt.Sprintf("https://dlsoft.dmm.co.jp/detail/%s/", c.ID)
+	case "getchu":
+		return fmt.Sprintf("https://www.getchu.com/soft.phtml?id=%s", c.ID)
+	case "bangumi":
+		return fmt.Sprintf("https://api.bgm.tv/v0/subjects/%s", c.ID)
+	case "2dfan":
+		return fmt.Sprintf("https://2dfan.org/subjects/%s", c.ID)
+	case "ggbases":
+		return fmt.Sprintf("https://ggbases.dlgal.com/view.so?id=%s", c.ID)
+	case "vndb":
+		return fmt.Sprintf("https://vndb.org/%s", c.ID)
+	}
+	return ""
+}
+
+type codePattern struct {
+	source    string
+	re        *regexp.Regexp
+	normalize func(m []string) string
+	bare      bool // 裸编号，前后不能紧挨字母或数字
+}
+
+func lastGroup(m []string) string {
+	return m[len(m)-1]
+}
+
+// 链接形式的规则放在前面，裸编号的规则放在后面，同一位置只取第一个匹配
+var codePatterns = []codePattern{
+	{"getchu", regexp.MustCompile(`(?i)getchu\.com/soft\.phtml\?(?:[^\s"'<>]*&)?id=(\d+)`), lastGroup, false},
+	{"bangumi", regexp.MustCompile(`(?i)(?:bgm\.tv|bangumi\.tv|chii\.in)/(?:v0/)?subjects?/(\d+)`), lastGroup, false},
+	{"2dfan", regexp.MustCompile(`(?i)2dfan\.(?:com|org)/subjects/(\d+)`), lastGroup, false},
+	{"ggbases", regexp.MustCompile(`(?i)ggbases\.[\w.]+/view\.so\?(?:[^\s"'<>]*&)?id=(\d+)`), lastGroup, false},
+	{"fanza", regexp.MustCompile(`(?i)(?:dmm\.co\.jp|dmm\.com)/[^\s"'<>]*?cid=([a-z0-9_]+)`), func(m []string) string {
+		return strings.ToLower(m[1])
+	}, false},
+	{"fanza", regexp.MustCompile(`(?i)dlsoft\.dmm\.co\.jp/detail/([a-z0-9_]+)`), func(m []string) string {
+		return strings.ToLower(m[1])
+	}, false},
+	{"vndb", regexp.MustCompile(`(?i)\bvndb\.org/(v\d+)\b`), func(m []string) string {
+		return strings.ToLower(m[1])
+	}, false},
+	{"dlsite", regexp.MustCompile(`(?i)(RJ|VJ|BJ)(\d{8}|\d{6})`), func(m []string) string {
+		return strings.ToUpper(m[1]) + m[2]
+	}, true},
+	{"getchu", regexp.MustCompile(`(?i)getchu[-_ ]?(\d{5,8})`), lastGroup, true},
+	// 裸的 v 编号容易与版本号混淆，必须带 vndb 前缀
+	{"vndb", regexp.MustCompile(`(?i)vndb[-_ :]?(v\d{1,6})`), func(m []string) string {
+		return strings.ToLower(m[1])
+	}, true},
+}
+
+// FindCodes 找出字符串中所有的商品编号，按出现顺序返回并去重
+//
+// 支持 DLsite RJ/VJ/BJ 编号、DMM/Fanza cid、Getchu、Bangumi、2dfan、GGBases 的详情页链接以及带 vndb 前缀的 VNDB v 编号。
+func FindCodes(s string) []Code {
+	var codes []Code
+	seen := make(map[Code]bool)
+	for _, f := range findCodes(s) {
+		if seen[f.code] {
+			continue
+		}
+		seen[f.code] = true
+		codes = append(codes, f.code)
+	}
+	return codes
+}
+
+// StripCodes 删除字符串中识别出的商品编号，用于从文件名中提取标题
+func StripCodes(s string) string {
+	b := strings.Builder{}
+	last := 0
+	for _, f := range findCodes(s) {
+		b.WriteString(s[last:f.start])
+		b.WriteByte(' ')
+		last = f.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+type foundCode struct {
+	start, end int
+	code       Code
+}
+
+func findCodes(s string) []foundCode {
+	var all []foundCode
+	for _, p := range codePatterns {
+		for _, idx := range p.re.FindAllStringSubmatchIndex(s, -1) {
+			if p.bare && (isAlnum(s, idx[0]-1) || isAlnum(s, idx[1])) {
+				continue
+			}
+			m := make([]string, len(idx)/2)
+			for i := range m {
+				if idx[2*i] >= 0 {
+					m[i] = s[idx[2*i]:idx[2*i+1]]
+				}
+			}
+			all = append(all, foundCode{idx[0], idx[1], Code{Source: p.source, ID: p.normalize(m)}})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].start < all[j].start
+	})
+
+	var codes []foundCode
+	end := -1
+	for _, f := range all {
+		// 被更早的匹配覆盖的部分不再重复识别，例如链接中的编号
+		if f.start < end {
+			continue
+		}
+		end = f.end
+		codes = append(codes, f)
+	}
+	return codes
+}
+
+// ParseCode 返回字符串中的第一个商品编号
+func ParseCode(s string) (Code, bool) {
+	codes := FindCodes(s)
+	if len(codes) == 0 {
+		return Code{}, false
+	}
+	return codes[0], true
+}
+
+func isAlnum(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
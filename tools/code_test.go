@@ -0,0 +1,63 @@
+package tools
+
+import "testing"
+
+func TestFindCodes(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []Code
+	}{
+		{"[RJ123456] title rj01234567_x", []Code{{"dlsite", "RJ123456"}, {"dlsite", "RJ01234567"}}},
+		{"VJ012345 BJ654321 RJ1234567", []Code{{"dlsite", "VJ012345"}, {"dlsite", "BJ654321"}}},
+		{"https://www.getchu.com/soft.phtml?id=1219845&gc=gc", []Code{{"getchu", "1219845"}}},
+		{"getchu-1232405", []Code{{"getchu", "1232405"}}},
+		{"https://bgm.tv/subject/226254 https://api.bgm.tv/v0/subjects/226254", []Code{{"bangumi", "226254"}}},
+		{"https://2dfan.com/subjects/4566/walkthroughs", []Code{{"2dfan", "4566"}}},
+		{"https://ggbases.dlgal.com/view.so?id=119583", []Code{{"ggbases", "119583"}}},
+		{"https://dlsoft.dmm.co.jp/detail/views_0123/", []Code{{"fanza", "views_0123"}}},
+		{"https://www.dmm.co.jp/dc/pcgame/-/detail/=/cid=AMP_0001/", []Code{{"fanza", "amp_0001"}}},
+		{"vndb v17 and https://vndb.org/v2002", []Code{{"vndb", "v17"}, {"vndb", "v2002"}}},
+		{"dev2 version 1.2", nil},
+		{"vndb:V17 vndb_v18", []Code{{"vndb", "v17"}, {"vndb", "v18"}}},
+		{"[Brand] Title v1.02", nil},
+		{"Game_RJ123456_v2", []Code{{"dlsite", "RJ123456"}}},
+	}
+	for _, c := range cases {
+		got := FindCodes(c.in)
+		if len(got) != len(c.want) {
+			t.Errorf("FindCodes(%q) = %v, want %v", c.in, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("FindCodes(%q) = %v, want %v", c.in, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCode_URL(t *testing.T) {
+	cases := map[Code]string{
+		{"dlsite", "RJ123456"}: "https://www.dlsite.com/maniax/work/=/product_id/RJ123456.html",
+		{"dlsite", "VJ012345"}: "https://www.dlsite.com/pro/work/=/product_id/VJ012345.html",
+		{"getchu", "1219845"}:  "https://www.getchu.com/soft.phtml?id=1219845",
+		{"bangumi", "226254"}:  "https://api.bgm.tv/v0/subjects/226254",
+		{"vndb", "v17"}:        "https://vndb.org/v17",
+		{"dlsite", "R"}:        "https://www.dlsite.com/maniax/work/=/product_id/R.html",
+	}
+	for code, want := range cases {
+		if got := code.URL(); got != want {
+			t.Errorf("%v.URL() = %s, want %s", code, got, want)
+		}
+	}
+}
+
+func TestStripCodes(t *testing.T) {
+	if got := StripCodes("getchu_1219845 タイトル RJ123456"); got != "  タイトル  " {
+		t.Errorf("StripCodes = %q", got)
+	}
+	if got := StripCodes("[Brand] Title v1.02"); got != "[Brand] Title v1.02" {
+		t.Errorf("StripCodes should keep version numbers, got %q", got)
+	}
+}
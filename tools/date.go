@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var dateRe = regexp.MustCompile(`(\d{4})\s*[-/.年]\s*(\d{1,2})(?:\s*[-/.月]\s*(\d{1,2}))?`)
+
+// NormalizeDate 将 "2019/1/25"、"2019年01月25日"、"2019-01" 之类的日期统一为 2006-01-02 格式
+//
+// 只有年月时返回 2006-01，无法识别时返回空字符串。
+func NormalizeDate(s string) string {
+	m := dateRe.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	month, _ := strconv.Atoi(m[2])
+	if month < 1 || month > 12 {
+		return ""
+	}
+	if m[3] == "" {
+		return fmt.Sprintf("%s-%02d", m[1], month)
+	}
+	day, _ := strconv.Atoi(m[3])
+	if day < 1 || day > 31 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%02d-%02d", m[1], month, day)
+}
@@ -0,0 +1,20 @@
+package tools
+
+import "testing"
+
+func TestNormalizeDate(t *testing.T) {
+	cases := map[string]string{
+		"2019-01-25":     "2019-01-25",
+		"2019/1/5":       "2019-01-05",
+		"2023年2月24日":     "2023-02-24",
+		"発売日：2015/10/23": "2015-10-23",
+		"2023-02":        "2023-02",
+		"2023/13/01":     "",
+		"TBA":            "",
+	}
+	for in, want := range cases {
+		if got := NormalizeDate(in); got != want {
+			t.Errorf("NormalizeDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
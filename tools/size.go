@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 大小单位对应的 1000/1024 幂次
+var sizeUnits = map[string]int{
+	"":     0,
+	"b":    0,
+	"byte": 0,
+	"字节":   0,
+	"k":    1,
+	"kb":   1,
+	"kib":  1,
+	"千字节":  1,
+	"m":    2,
+	"mb":   2,
+	"mib":  2,
+	"兆":    2,
+	"兆字节":  2,
+	"g":    3,
+	"gb":   3,
+	"gib":  3,
+	"千兆":   3,
+	"吉":    3,
+	"吉字节":  3,
+	"t":    4,
+	"tb":   4,
+	"tib":  4,
+	"太":    4,
+	"太字节":  4,
+}
+
+// ParseSize 将 "3.2G"、"700 MB"、"1.5GiB"、"3.2 千兆" 之类的大小文本解析为字节数
+//
+// 带 iB 的单位始终按 1024 换算；其余单位在 decimal 为 true 时按 1000 换算，否则按 1024 换算。
+func ParseSize(s string, decimal bool) (int64, error) {
+	value, exp, binary, err := splitSize(s)
+	if err != nil {
+		return 0, err
+	}
+	base := 1024.0
+	if decimal && !binary {
+		base = 1000
+	}
+	return int64(math.Round(value * math.Pow(base, float64(exp)))), nil
+}
+
+// SizeMatch 判断大小文本与实际字节数是否相符
+//
+// 网站上的大小通常只保留一两位小数且不区分十进制/二进制，
+// 因此两种换算任一落在 tolerance（相对误差）以内即视为相符。
+func SizeMatch(s string, actual int64, tolerance float64) bool {
+	for _, decimal := range []bool{false, true} {
+		size, err := ParseSize(s, decimal)
+		if err != nil || size <= 0 {
+			return false
+		}
+		if math.Abs(float64(actual-size)) <= float64(size)*tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func splitSize(s string) (value float64, exp int, binary bool, err error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	if s == "" {
+		return 0, 0, false, errors.New("大小为空")
+	}
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	value, err = strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("无法解析大小 %q: %v", s, err)
+	}
+	unit := strings.ToLower(strings.TrimSpace(s[i:]))
+	unit = strings.TrimSuffix(unit, "s")
+	exp, ok := sizeUnits[unit]
+	if !ok {
+		return 0, 0, false, fmt.Errorf("未知的大小单位 %q", s[i:])
+	}
+	return value, exp, strings.HasSuffix(unit, "ib"), nil
+}
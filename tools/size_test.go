@@ -0,0 +1,49 @@
+package tools
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		in      string
+		decimal bool
+		want    int64
+	}{
+		{"3.2G", false, 3435973837},
+		{"3.2G", true, 3200000000},
+		{"700 MB", false, 700 << 20},
+		{"700 MB", true, 700000000},
+		{"1.5GiB", true, 1610612736},
+		{"512k", false, 512 << 10},
+		{"2.5 千兆", false, 2684354560},
+		{"800兆", true, 800000000},
+		{"1,024 bytes", false, 1024},
+	}
+	for _, c := range cases {
+		got, err := ParseSize(c.in, c.decimal)
+		if err != nil {
+			t.Errorf("ParseSize(%q) err: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseSize(%q, %v) = %d, want %d", c.in, c.decimal, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"", "G", "3.2 lightyears"} {
+		if _, err := ParseSize(in, false); err == nil {
+			t.Errorf("ParseSize(%q) expected error", in)
+		}
+	}
+}
+
+func TestSizeMatch(t *testing.T) {
+	if !SizeMatch("3.2G", 3400000000, 0.05) {
+		t.Error("3.2G should match 3.4e9 bytes")
+	}
+	if !SizeMatch("3.2GB", 3210000000, 0.05) {
+		t.Error("3.2GB should match 3.21e9 bytes")
+	}
+	if SizeMatch("3.2G", 1<<30, 0.05) {
+		t.Error("3.2G should not match 1GiB")
+	}
+}
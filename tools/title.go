@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 简体字、繁体字到日文新字体的映射，只收录标题中常见的字
+var kanjiPairs = []string{
+	"恋戀", "戦战戰", "剣剑劍", "気气氣", "楽乐樂", "愛爱", "説说說", "話话", "語语",
+	"時时", "記记", "伝传傳", "竜龙龍", "華华", "姫姬", "関关關", "東东", "門门", "開开",
+	"間间", "馬马", "鳥鸟", "魚鱼", "書书", "長长", "発发發", "後后", "応应應", "鉄铁鐵",
+	"様样樣", "動动", "機机", "電电", "視视", "覚觉覺", "見见", "個个", "還还", "進进",
+	"遠远", "運运", "連连", "過过", "辺边邊", "変变變", "悪恶惡", "絶绝", "経经經", "結结",
+	"続续續", "紅红", "純纯", "級级", "線线", "練练", "組组", "終终", "約约", "織织",
+	"園园", "円圆圓", "団团團", "図图圖", "実实實", "対对對", "専专專", "島岛", "師师",
+	"広广廣", "帰归歸", "従从從", "総总總", "戯戏戲", "護护", "択择擇", "無无", "殺杀",
+	"権权權", "極极", "歓欢歡", "漢汉", "沢泽澤", "満满滿", "霊灵靈", "為为", "熱热",
+	"獄狱", "猟猎獵", "環环", "現现", "離离", "穏稳穩", "競竞", "筆笔", "類类", "緊紧",
+	"縁缘", "羅罗", "義义", "習习", "聖圣", "聞闻", "職职", "脳脑腦", "芸艺藝", "節节",
+	"薬药藥", "補补", "裏裡", "観观觀", "計计", "認认", "譲让讓", "訓训", "講讲", "詩诗",
+	"訳译譯", "試试", "読读讀", "誰谁", "調调", "談谈", "謎谜", "貝贝", "負负", "貴贵",
+	"資资", "賞赏", "軽轻輕", "転转轉", "輝辉", "適适", "選选", "遺遗", "隣邻鄰", "針针",
+	"鐘钟", "鎖锁", "鏡镜", "閃闪", "閉闭", "問问", "陰阴", "陽阳", "隊队", "際际",
+	"陸陆", "険险險", "隠隐隱", "難难", "頂顶", "項项", "順顺", "領领", "頭头", "題题",
+	"顔颜顏", "風风", "飛飞", "飯饭", "館馆", "駆驱驅", "験验驗", "鶏鸡雞", "黒黑", "斉齐齊",
+	"齢龄齡", "学學", "国國", "会會", "声聲", "売卖賣", "桜樱櫻", "夢梦",
+	"帯带帶", "灯燈", "与與", "万萬", "乱乱亂", "仮假", "価价價", "剤剂劑", "単单單",
+	"巻卷", "弾弹彈", "径徑", "恵惠", "戻戾", "拡扩擴", "掲揭", "摂摄攝", "数数數", "断断斷",
+	"暁晓曉", "枢枢樞", "桟栈棧", "検检檢", "歩步", "歳岁歲", "残残殘", "涙泪淚", "焼烧燒",
+	"獣兽獸", "畳叠疊", "県县縣", "砕碎", "穂穗", "粋粹", "継继繼", "縄绳繩", "蔵藏", "虫蟲",
+	"蛍萤螢", "衛卫衛", "装装裝", "触触觸", "証证證", "讃赞讚", "豊丰豐", "賛赞贊", "践践踐",
+	"鋭锐", "録录錄", "闘鬥", "亜亚亞", "悩恼惱", "掛挂", "歴历歷", "処处處",
+}
+
+var (
+	kanjiFold = func() map[rune]rune {
+		m := make(map[rune]rune)
+		for _, pair := range kanjiPairs {
+			runes := []rune(pair)
+			for _, r := range runes[1:] {
+				if r != runes[0] {
+					m[r] = runes[0]
+				}
+			}
+		}
+		return m
+	}()
+
+	// 版本说明，例如 (初回版)、【DL版】、 通常版
+	editionWords = `初回限定版|初回版|限定版|通常版|豪華版|完全版|廉価版|新装版|普及版|DL版|ダウンロード版|パッケージ版|体験版|Steam版|Special Edition|Limited Edition|Deluxe Edition`
+	editionRe    = regexp.MustCompile(`(?i)[\[(【「『]\s*[^\])】」』]*?(?:` + editionWords + `|版)\s*[\])】」』]|\s+(?:` + editionWords + `)\s*$`)
+)
+
+// NormalizeTitle 规范化标题，便于跨数据源比较
+//
+// 依次进行 NFKC、小写、版本说明去除、波浪线/连字符/引号等标点折叠以及简繁日汉字折叠。
+func NormalizeTitle(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	s = editionRe.ReplaceAllString(s, " ")
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '〜', '～', '∼', '〰':
+			return '~'
+		case '‐', '‑', '‒', '–', '—', '―', '−':
+			return '-'
+		case '・', '･', '·', '•':
+			return ' '
+		case '「', '」', '『', '』', '“', '”', '‘', '’', '"', '\'', '《', '》', '〈', '〉':
+			return ' '
+		case '　':
+			return ' '
+		}
+		if k, ok := kanjiFold[r]; ok {
+			return k
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// TitleKey 规范化后去除所有空白和标点，用于判断两个标题是否相同
+func TitleKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, NormalizeTitle(s))
+}
+
+// TitleSimilarity 标题相似度，返回 0~1
+//
+// 取字符二元组 Dice 系数与编辑距离相似度中的较大者，前者对词序变化不敏感，后者对短标题更准确。
+func TitleSimilarity(a, b string) float64 {
+	ka, kb := []rune(TitleKey(a)), []rune(TitleKey(b))
+	if len(ka) == 0 || len(kb) == 0 {
+		return 0
+	}
+	if string(ka) == string(kb) {
+		return 1
+	}
+	dice := diceCoefficient(ka, kb)
+	maxLen := len(ka)
+	if len(kb) > maxLen {
+		maxLen = len(kb)
+	}
+	edit := 1 - float64(EditDistance(string(ka), string(kb)))/float64(maxLen)
+	if dice > edit {
+		return dice
+	}
+	return edit
+}
+
+// EditDistance 按字符计算的 Levenshtein 距离
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func diceCoefficient(a, b []rune) float64 {
+	ga, gb := bigrams(a), bigrams(b)
+	common, total := 0, 0
+	for g, n := range ga {
+		total += n
+		if m := gb[g]; m > 0 {
+			common += minInt(n, m)
+		}
+	}
+	for _, n := range gb {
+		total += n
+	}
+	return 2 * float64(common) / float64(total)
+}
+
+func bigrams(runes []rune) map[string]int {
+	grams := make(map[string]int)
+	if len(runes) == 1 {
+		grams[string(runes)]++
+		return grams
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+func minInt(n int, ns ...int) int {
+	for _, m := range ns {
+		if m < n {
+			n = m
+		}
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package tools
+
+import "testing"
+
+func TestNormalizeTitle(t *testing.T) {
+	cases := map[string]string{
+		"ＡＢＣ　ｄｅｆ":            "abc def",
+		"恋×シンアイ彼女 〜Another〜": "恋×シンアイ彼女 ~another~",
+		"恋×シンアイ彼女 ～Another～": "恋×シンアイ彼女 ~another~",
+		"タイトル（初回版）":          "タイトル",
+		"タイトル【DL版】":          "タイトル",
+		"タイトル 通常版":           "タイトル",
+		"光装剑姬":               "光装剣姫",
+		"光裝劍姬":               "光装剣姫",
+		"「サクラ」・ノーツ":          "サクラ ノーツ",
+	}
+	for in, want := range cases {
+		if got := NormalizeTitle(in); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	if s := TitleSimilarity("光装剣姫アークブレイバー 魔族篇（初回版）", "光装剑姬アークブレイバー 魔族篇"); s != 1 {
+		t.Errorf("expected identical titles, got %f", s)
+	}
+	if s := TitleSimilarity("サクラノ詩 -櫻の森の上を舞う-", "サクラノ詩"); s < 0.3 || s >= 1 {
+		t.Errorf("unexpected partial similarity %f", s)
+	}
+	if s := TitleSimilarity("サクラノ詩", "まったく別のゲーム"); s > 0.2 {
+		t.Errorf("unexpected similarity for unrelated titles %f", s)
+	}
+	if s := TitleSimilarity("", "abc"); s != 0 {
+		t.Errorf("empty title similarity = %f", s)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"恋愛", "恋爱", 1},
+		{"same", "same", 0},
+	}
+	for _, c := range cases {
+		if got := EditDistance(c.a, c.b); got != c.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
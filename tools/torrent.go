@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+)
+
+// TorrentFile 种子中的单个文件
+type TorrentFile struct {
+	Path   string // 文件相对路径
+	Length int64  // 文件大小（字节）
+}
+
+// Torrent 种子文件中的元信息
+type Torrent struct {
+	InfoHash    string        // info 字典的 SHA-1，十六进制小写
+	Name        string        // 名称
+	PieceLength int64         // 分块大小
+	Length      int64         // 总大小
+	Files       []TorrentFile // 文件列表
+	Trackers    []string      // tracker 列表
+}
+
+// ParseTorrentFile 读取并解析本地的 .torrent 文件
+func ParseTorrentFile(name string) (*Torrent, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTorrent(data)
+}
+
+// ParseTorrent 解析 .torrent 文件内容
+func ParseTorrent(data []byte) (*Torrent, error) {
+	d := &bdecoder{data: data}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("torrent: 顶层不是字典")
+	}
+	info, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("torrent: 缺少 info 字典")
+	}
+
+	sum := sha1.Sum(d.raw["info"])
+	t := &Torrent{
+		InfoHash:    hex.EncodeToString(sum[:]),
+		Name:        utf8Field(info, "name"),
+		PieceLength: intField(info, "piece length"),
+	}
+
+	if files, ok := info["files"].([]interface{}); ok {
+		// 多文件种子
+		for _, f := range files {
+			file, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			parts, ok := file["path.utf-8"].([]interface{})
+			if !ok {
+				parts, _ = file["path"].([]interface{})
+			}
+			elems := []string{t.Name}
+			for _, p := range parts {
+				if s, ok := p.(string); ok {
+					elems = append(elems, s)
+				}
+			}
+			length := intField(file, "length")
+			t.Files = append(t.Files, TorrentFile{Path: path.Join(elems...), Length: length})
+			t.Length += length
+		}
+	} else {
+		// 单文件种子
+		t.Length = intField(info, "length")
+		t.Files = []TorrentFile{{Path: t.Name, Length: t.Length}}
+	}
+
+	seen := make(map[string]bool)
+	addTracker := func(v interface{}) {
+		if s, ok := v.(string); ok && s != "" && !seen[s] {
+			seen[s] = true
+			t.Trackers = append(t.Trackers, s)
+		}
+	}
+	addTracker(root["announce"])
+	if tiers, ok := root["announce-list"].([]interface{}); ok {
+		for _, tier := range tiers {
+			if list, ok := tier.([]interface{}); ok {
+				for _, tr := range list {
+					addTracker(tr)
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+// Magnet 根据种子信息生成磁力链接
+func (t *Torrent) Magnet() string {
+	magnet := fmt.Sprintf("magnet:?xt=urn:btih:%s", t.InfoHash)
+	if t.Name != "" {
+		magnet += "&dn=" + url.QueryEscape(t.Name)
+	}
+	for _, tr := range t.Trackers {
+		magnet += "&tr=" + url.QueryEscape(tr)
+	}
+	return magnet
+}
+
+func utf8Field(dict map[string]interface{}, key string) string {
+	if s, ok := dict[key+".utf-8"].(string); ok {
+		return s
+	}
+	s, _ := dict[key].(string)
+	return s
+}
+
+func intField(dict map[string]interface{}, key string) int64 {
+	n, _ := dict[key].(int64)
+	return n
+}
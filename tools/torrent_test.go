@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseTorrent(t *testing.T) {
+	info := "d5:filesl" +
+		"d6:lengthi1000e4:pathl4:data8:game.binee" +
+		"d6:lengthi24e4:pathl10:readme.txtee" +
+		"e4:name4:Game12:piece lengthi16384e6:pieces0:e"
+	data := "d8:announce18:http://a.example/a13:announce-listll18:http://a.example/ael18:http://b.example/bee4:info" + info + "e"
+
+	tr, err := ParseTorrent([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(info))
+	if tr.InfoHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("InfoHash = %s", tr.InfoHash)
+	}
+	if tr.Name != "Game" || tr.PieceLength != 16384 || tr.Length != 1024 {
+		t.Errorf("unexpected torrent %+v", tr)
+	}
+	if len(tr.Files) != 2 || tr.Files[0].Path != "Game/data/game.bin" || tr.Files[1].Length != 24 {
+		t.Errorf("unexpected files %+v", tr.Files)
+	}
+	if len(tr.Trackers) != 2 {
+		t.Errorf("unexpected trackers %v", tr.Trackers)
+	}
+	magnet := tr.Magnet()
+	if !strings.HasPrefix(magnet, "magnet:?xt=urn:btih:"+tr.InfoHash+"&dn=Game&tr=") {
+		t.Errorf("unexpected magnet %s", magnet)
+	}
+}
+
+func TestBDecode(t *testing.T) {
+	v, err := BDecode([]byte("li-3e3:abcd1:kli1eeee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := v.([]interface{})
+	if list[0].(int64) != -3 || list[1].(string) != "abc" {
+		t.Errorf("unexpected %v", list)
+	}
+	for _, bad := range []string{"i3", "5:ab", "l", "d1:a", "x", "i1ei2e"} {
+		if _, err := BDecode([]byte(bad)); err == nil {
+			t.Errorf("BDecode(%q) expected error", bad)
+		}
+	}
+}